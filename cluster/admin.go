@@ -0,0 +1,32 @@
+package cluster
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+//StatusHandler serves the current cluster membership and, for each
+//endpoint this node has an opinion about, whether that opinion is
+//locally observed or learned via gossip. Mount it alongside xavi's other
+//admin endpoints, e.g. mux.Handle("/admin/cluster", cluster.StatusHandler()).
+func StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := active
+		if c == nil {
+			w.WriteHeader(http.StatusNotImplemented)
+			json.NewEncoder(w).Encode(map[string]string{"error": "cluster mode is not enabled"})
+			return
+		}
+
+		endpointSources := make(map[string]string)
+		for addr, source := range c.Status() {
+			endpointSources[addr] = source.String()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"members":   c.Members(),
+			"endpoints": endpointSources,
+		})
+	})
+}
@@ -0,0 +1,134 @@
+//Package cluster gives a fleet of xavi nodes a shared view of backend
+//endpoint health over gossip, so a failure detected by one node is
+//honored by all of them rather than each node running its own isolated
+//health checks. It wires into loadbalancer via SetClusterHooks: only the
+//node elected owner for a given endpoint runs that endpoint's health
+//check, and up/down transitions are replicated to the rest of the
+//cluster over memberlist broadcasts.
+package cluster
+
+import (
+	"os"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hashicorp/memberlist"
+	"github.com/xtracdev/xavi/loadbalancer"
+)
+
+const (
+	//BindEnvVar is the host:port memberlist listens on for gossip traffic.
+	BindEnvVar = "XAVI_CLUSTER_BIND"
+	//JoinEnvVar is a comma-separated list of existing cluster members to
+	//join at startup.
+	JoinEnvVar = "XAVI_CLUSTER_JOIN"
+)
+
+//Cluster is a running membership of xavi nodes sharing endpoint health
+//state over gossip.
+type Cluster struct {
+	list *memberlist.Memberlist
+
+	mutex     sync.RWMutex
+	endpoints map[string]endpointStatus
+}
+
+type endpointStatus struct {
+	up     bool
+	source loadbalancer.EndpointSource
+}
+
+var active *Cluster
+
+//Join starts gossip membership using the XAVI_CLUSTER_BIND and
+//XAVI_CLUSTER_JOIN environment variables and wires the resulting cluster
+//into loadbalancer's health check ownership and status replication
+//hooks. If XAVI_CLUSTER_BIND is not set, clustering is disabled and every
+//node behaves as it did before: single-node, all checks local.
+func Join() (*Cluster, error) {
+	bind := os.Getenv(BindEnvVar)
+	if bind == "" {
+		log.Info(BindEnvVar, " not set - running without cluster gossip")
+		return nil, nil
+	}
+
+	conf := memberlist.DefaultLANConfig()
+	conf.Name = bind
+	host, port, err := splitHostPort(bind)
+	if err != nil {
+		return nil, err
+	}
+	conf.BindAddr = host
+	conf.BindPort = port
+
+	c := &Cluster{endpoints: make(map[string]endpointStatus)}
+	conf.Events = &memberEventDelegate{cluster: c}
+	conf.Delegate = &gossipDelegate{cluster: c}
+
+	list, err := memberlist.Create(conf)
+	if err != nil {
+		return nil, err
+	}
+	c.list = list
+
+	if joinAddrs := os.Getenv(JoinEnvVar); joinAddrs != "" {
+		if _, err := list.Join(splitJoinAddrs(joinAddrs)); err != nil {
+			log.Warn("error joining cluster via ", JoinEnvVar, ": ", err.Error())
+		}
+	}
+
+	active = c
+	loadbalancer.SetClusterHooks(c.ownsHealthCheck, c.publishEndpointStatus)
+
+	log.Info("joined cluster as ", bind, " with ", len(list.Members()), " known member(s)")
+
+	return c, nil
+}
+
+//Members returns the name of every node currently known to the cluster.
+func (c *Cluster) Members() []string {
+	var names []string
+	for _, m := range c.list.Members() {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+//ownsHealthCheck reports whether this node is the elected owner of the
+//health check for address, per consistent hashing over the member set.
+func (c *Cluster) ownsHealthCheck(address string) bool {
+	return ownerOf(address, c.Members()) == c.list.LocalNode().Name
+}
+
+//publishEndpointStatus records a locally-observed up/down transition and
+//broadcasts it to the rest of the cluster.
+func (c *Cluster) publishEndpointStatus(address string, up bool) {
+	c.mutex.Lock()
+	c.endpoints[address] = endpointStatus{up: up, source: loadbalancer.SourceLocal}
+	c.mutex.Unlock()
+
+	msg := encodeStatusUpdate(address, up).Message()
+	local := c.list.LocalNode().Name
+	for _, m := range c.list.Members() {
+		if m.Name == local {
+			continue
+		}
+		if err := c.list.SendBestEffort(m, msg); err != nil {
+			log.Warn("error sending endpoint status to ", m.Name, ": ", err.Error())
+		}
+	}
+}
+
+//Status returns the source (local vs. gossiped) of the status the
+//cluster currently knows for every endpoint it has seen a transition
+//for. The up/down value itself is not included.
+func (c *Cluster) Status() map[string]loadbalancer.EndpointSource {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	status := make(map[string]loadbalancer.EndpointSource, len(c.endpoints))
+	for addr, s := range c.endpoints {
+		status[addr] = s.source
+	}
+	return status
+}
@@ -0,0 +1,76 @@
+package cluster
+
+import (
+	"encoding/json"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/hashicorp/memberlist"
+	"github.com/xtracdev/xavi/loadbalancer"
+)
+
+//statusUpdate is gossiped whenever a node observes an endpoint transition
+//on a health check it owns.
+type statusUpdate struct {
+	Address string `json:"address"`
+	Up      bool   `json:"up"`
+}
+
+func encodeStatusUpdate(address string, up bool) *statusBroadcast {
+	payload, _ := json.Marshal(statusUpdate{Address: address, Up: up})
+	return &statusBroadcast{payload: payload}
+}
+
+//statusBroadcast implements memberlist.Broadcast for a single endpoint
+//status update.
+type statusBroadcast struct {
+	payload []byte
+}
+
+func (b *statusBroadcast) Invalidates(other memberlist.Broadcast) bool { return false }
+func (b *statusBroadcast) Message() []byte                             { return b.payload }
+func (b *statusBroadcast) Finished()                                   {}
+
+//gossipDelegate receives gossiped status updates and applies them as
+//EndpointSource.SourceGossiped entries, since this node did not run the
+//health check itself.
+type gossipDelegate struct {
+	cluster *Cluster
+}
+
+func (d *gossipDelegate) NodeMeta(limit int) []byte { return nil }
+
+func (d *gossipDelegate) NotifyMsg(msg []byte) {
+	var update statusUpdate
+	if err := json.Unmarshal(msg, &update); err != nil {
+		log.Warn("error decoding gossiped endpoint status: ", err.Error())
+		return
+	}
+
+	d.cluster.mutex.Lock()
+	d.cluster.endpoints[update.Address] = endpointStatus{up: update.Up, source: loadbalancer.SourceGossiped}
+	d.cluster.mutex.Unlock()
+
+	loadbalancer.ApplyGossipedStatus(update.Address, update.Up)
+}
+
+func (d *gossipDelegate) GetBroadcasts(overhead, limit int) [][]byte { return nil }
+func (d *gossipDelegate) LocalState(join bool) []byte                { return nil }
+func (d *gossipDelegate) MergeRemoteState(buf []byte, join bool)      {}
+
+//memberEventDelegate just logs membership changes so operators can see
+//the cluster forming from the logs.
+type memberEventDelegate struct {
+	cluster *Cluster
+}
+
+func (d *memberEventDelegate) NotifyJoin(n *memberlist.Node) {
+	log.Info("cluster member joined: ", n.Name)
+}
+
+func (d *memberEventDelegate) NotifyLeave(n *memberlist.Node) {
+	log.Info("cluster member left: ", n.Name)
+}
+
+func (d *memberEventDelegate) NotifyUpdate(n *memberlist.Node) {
+	log.Info("cluster member updated: ", n.Name)
+}
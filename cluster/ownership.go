@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//virtualNodesPerMember is how many points each member occupies on the
+//hash ring. Spreading each member across many points keeps ownership
+//balanced across members of a small cluster, where a single point per
+//member would otherwise hash unevenly.
+const virtualNodesPerMember = 100
+
+//ring is a consistent hash ring: point hashes in ascending order, each
+//owned by one member. Looking up a key walks clockwise from its hash to
+//the next point on the ring.
+type ring []ringPoint
+
+type ringPoint struct {
+	hash   uint64
+	member string
+}
+
+func (r ring) Len() int           { return len(r) }
+func (r ring) Less(i, j int) bool { return r[i].hash < r[j].hash }
+func (r ring) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+//buildRing places each member at virtualNodesPerMember points around the
+//ring, so adding or removing a member only reshuffles ownership of the
+//keys that hashed near its points, rather than every key in the cluster.
+func buildRing(members []string) ring {
+	r := make(ring, 0, len(members)*virtualNodesPerMember)
+	for _, member := range members {
+		for v := 0; v < virtualNodesPerMember; v++ {
+			r = append(r, ringPoint{hash: hashKey(fmt.Sprintf("%s#%d", member, v)), member: member})
+		}
+	}
+	sort.Sort(r)
+	return r
+}
+
+//ownerOf deterministically picks one member from members to own the
+//health check for address, using consistent hashing over a ring of each
+//member's virtual nodes: address hashes to a point on the ring and the
+//next member clockwise from it owns the check. Adding or removing a
+//member only reshuffles ownership for the endpoints that hashed near the
+//change instead of stampeding every backend with new checks at once.
+func ownerOf(address string, members []string) string {
+	if len(members) == 0 {
+		return ""
+	}
+
+	r := buildRing(members)
+	hash := hashKey(address)
+
+	idx := sort.Search(len(r), func(i int) bool { return r[i].hash >= hash })
+	if idx == len(r) {
+		idx = 0
+	}
+
+	return r[idx].member
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+func splitHostPort(bind string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(bind)
+	if err != nil {
+		return "", 0, err
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return host, port, nil
+}
+
+func splitJoinAddrs(joinAddrs string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(joinAddrs, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/xtracdev/xavi/kvstore"
+)
+
+//Key prefixes under which listener/route/backend/server definitions are
+//stored in the configuration KV store.
+const (
+	listenerConfigPrefix = "/xavi/listener/"
+	routeConfigPrefix    = "/xavi/route/"
+	backendConfigPrefix  = "/xavi/backend/"
+	serverConfigPrefix   = "/xavi/server/"
+)
+
+//ListenerConfig describes one xavi listener: the routes it dispatches
+//requests to, in the order they should be matched.
+type ListenerConfig struct {
+	Name       string
+	RouteNames []string
+}
+
+//RouteConfig describes one route: the URI prefix it's mounted at and the
+//backend it forwards matching requests to.
+type RouteConfig struct {
+	Name        string
+	URIRoot     string
+	BackendName string
+}
+
+//ReadListenerConfig reads the named listener definition from kvs.
+func ReadListenerConfig(name string, kvs kvstore.KVStore) (*ListenerConfig, error) {
+	var lc ListenerConfig
+	if err := readConfig(kvs, listenerConfigPrefix+name, &lc); err != nil {
+		return nil, err
+	}
+	return &lc, nil
+}
+
+//ReadRouteConfig reads the named route definition from kvs.
+func ReadRouteConfig(name string, kvs kvstore.KVStore) (*RouteConfig, error) {
+	var rc RouteConfig
+	if err := readConfig(kvs, routeConfigPrefix+name, &rc); err != nil {
+		return nil, err
+	}
+	return &rc, nil
+}
+
+//ReadBackendConfig reads the named backend definition from kvs.
+func ReadBackendConfig(name string, kvs kvstore.KVStore) (*BackendConfig, error) {
+	var bc BackendConfig
+	if err := readConfig(kvs, backendConfigPrefix+name, &bc); err != nil {
+		return nil, err
+	}
+	return &bc, nil
+}
+
+//ReadServerConfig reads the named server definition from kvs.
+func ReadServerConfig(name string, kvs kvstore.KVStore) (*ServerConfig, error) {
+	var sc ServerConfig
+	if err := readConfig(kvs, serverConfigPrefix+name, &sc); err != nil {
+		return nil, err
+	}
+	return &sc, nil
+}
+
+func readConfig(kvs kvstore.KVStore, key string, v interface{}) error {
+	data, err := kvs.Get(key)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("error unmarshalling config at %s: %s", key, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package config
+
+//ServerConfig describes a single backend server: where to reach it, how
+//to health check it, and how it should be weighted by the load balancer
+//policies that take weight into account.
+type ServerConfig struct {
+	Address                 string
+	Port                    int
+	PingURI                 string
+	HealthCheckIntervalSecs int
+	Weight                  int
+
+	//ClientCertPath and ClientKeyPath, when set, identify the client
+	//certificate xavi presents to this server for mTLS. They are
+	//populated at route-build time by the pki subsystem rather than read
+	//from static configuration, so they are blank until a backend's
+	//certs have actually been issued.
+	ClientCertPath string
+	ClientKeyPath  string
+}
+
+//Backend protocols understood by service.BuildServiceForListener when
+//constructing the proxy for a route's backend.
+const (
+	ProtocolHTTP = "http"
+	ProtocolGRPC = "grpc"
+)
+
+//BackendConfig describes a named pool of servers, the load balancer
+//policy used to pick among them, and the wire protocol spoken by the
+//servers in the pool. An empty Protocol is treated as ProtocolHTTP, so
+//existing backend definitions keep working unchanged.
+type BackendConfig struct {
+	Name               string
+	ServerNames        []string
+	LoadBalancerPolicy string
+	Protocol           string
+}
@@ -0,0 +1,28 @@
+package kvstore
+
+import (
+	"net/url"
+
+	"github.com/docker/libkv"
+	"github.com/docker/libkv/store"
+	"github.com/docker/libkv/store/boltdb"
+)
+
+//boltdbBucket is the single bucket xavi stores all configuration under.
+const boltdbBucket = "xavi"
+
+func init() {
+	boltdb.Register()
+	registerBackend("boltdb", newBoltdbStore)
+}
+
+//newBoltdbStore builds a KVStore backed by an embedded boltdb file at the
+//path given in the endpoint URL, e.g. boltdb:///path/to/file.
+func newBoltdbStore(endpoint *url.URL) (KVStore, error) {
+	kv, err := libkv.NewStore(store.BOLTDB, []string{endpoint.Path}, &store.Config{Bucket: boltdbBucket})
+	if err != nil {
+		return nil, err
+	}
+
+	return &libkvAdapter{backend: store.BOLTDB, store: kv}, nil
+}
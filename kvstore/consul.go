@@ -0,0 +1,19 @@
+package kvstore
+
+import (
+	"net/url"
+
+	"github.com/docker/libkv/store"
+	"github.com/docker/libkv/store/consul"
+)
+
+func init() {
+	consul.Register()
+	registerBackend("consul", newConsulStore)
+}
+
+//newConsulStore builds a KVStore backed by a consul agent at the host:port
+//given in the endpoint URL, e.g. consul://127.0.0.1:8500.
+func newConsulStore(endpoint *url.URL) (KVStore, error) {
+	return newLibkvAdapter(store.CONSUL, endpoint, nil)
+}
@@ -0,0 +1,19 @@
+package kvstore
+
+import (
+	"net/url"
+
+	"github.com/docker/libkv/store"
+	"github.com/docker/libkv/store/etcd"
+)
+
+func init() {
+	etcd.Register()
+	registerBackend("etcd", newEtcdStore)
+}
+
+//newEtcdStore builds a KVStore backed by an etcd cluster at the host:port
+//given in the endpoint URL, e.g. etcd://127.0.0.1:2379.
+func newEtcdStore(endpoint *url.URL) (KVStore, error) {
+	return newLibkvAdapter(store.ETCD, endpoint, nil)
+}
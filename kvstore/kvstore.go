@@ -0,0 +1,162 @@
+//Package kvstore provides a backend-agnostic key/value store abstraction
+//for xavi configuration data. NewKVStore dispatches on the scheme of the
+//supplied URL so operators can point xavi at consul, etcd, zookeeper, or
+//an embedded boltdb file without any code changes.
+package kvstore
+
+import (
+	"fmt"
+	"net/url"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/libkv"
+	"github.com/docker/libkv/store"
+)
+
+//KVStore is the common surface every backend adapter implements. Get
+//returns ErrKeyNotFound when the key is absent so callers can distinguish
+//a missing key from a connectivity error.
+type KVStore interface {
+	Put(key string, value []byte) error
+	Get(key string) ([]byte, error)
+	List(prefix string) (map[string][]byte, error)
+	Delete(key string) error
+	Watch(key string, stopCh <-chan struct{}) (<-chan []byte, error)
+	AtomicPut(key string, value []byte) (bool, error)
+}
+
+//ErrKeyNotFound is returned by Get and Watch when the requested key does
+//not exist in the backing store.
+var ErrKeyNotFound = fmt.Errorf("key not found")
+
+//backendBuilder constructs a KVStore adapter given the parsed endpoint URL.
+type backendBuilder func(endpoint *url.URL) (KVStore, error)
+
+//backendRegistry maps a URL scheme to the adapter that handles it. Each
+//backend file registers itself here via an init function.
+var backendRegistry = make(map[string]backendBuilder)
+
+//registerBackend associates a scheme (e.g. "consul") with the builder
+//function used to construct a KVStore for that backend.
+func registerBackend(scheme string, builder backendBuilder) {
+	backendRegistry[scheme] = builder
+}
+
+//NewKVStore creates a KVStore implementation appropriate for the given
+//endpoint URL, e.g. consul://127.0.0.1:8500, etcd://127.0.0.1:2379,
+//zk://127.0.0.1:2181, or boltdb:///path/to/file. The scheme selects the
+//backend; everything else in the URL is backend-specific.
+func NewKVStore(endpoint string) (KVStore, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("Expected non-empty endpoint")
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse KV store endpoint %s: %s", endpoint, err.Error())
+	}
+
+	builder, ok := backendRegistry[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("No KV store backend registered for scheme %s", u.Scheme)
+	}
+
+	log.Info("Creating KV store of type ", u.Scheme, " for endpoint ", endpoint)
+	return builder(u)
+}
+
+//libkvAdapter adapts a github.com/docker/libkv store.Store to the xavi
+//KVStore interface so consul, etcd, and zk can share one implementation.
+type libkvAdapter struct {
+	backend store.Backend
+	store   store.Store
+}
+
+func newLibkvAdapter(backend store.Backend, endpoint *url.URL, config *store.Config) (KVStore, error) {
+	addrs := []string{endpoint.Host}
+	kv, err := libkv.NewStore(backend, addrs, config)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create %s KV store: %s", backend, err.Error())
+	}
+
+	return &libkvAdapter{backend: backend, store: kv}, nil
+}
+
+func (l *libkvAdapter) Put(key string, value []byte) error {
+	return l.store.Put(key, value, nil)
+}
+
+func (l *libkvAdapter) Get(key string) ([]byte, error) {
+	pair, err := l.store.Get(key)
+	if err == store.ErrKeyNotFound {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return pair.Value, nil
+}
+
+func (l *libkvAdapter) List(prefix string) (map[string][]byte, error) {
+	pairs, err := l.store.List(prefix)
+	if err == store.ErrKeyNotFound {
+		return map[string][]byte{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte)
+	for _, pair := range pairs {
+		values[pair.Key] = pair.Value
+	}
+
+	return values, nil
+}
+
+func (l *libkvAdapter) Delete(key string) error {
+	return l.store.Delete(key)
+}
+
+//AtomicPut creates key with value only if it does not already exist,
+//reporting (false, nil) rather than an error when another writer won the
+//race. This gives callers like pki.Bootstrap a compare-and-swap they can
+//use to decide a single winner among several nodes racing to initialize
+//the same key at startup.
+func (l *libkvAdapter) AtomicPut(key string, value []byte) (bool, error) {
+	_, _, err := l.store.AtomicPut(key, value, nil, nil)
+	if err == nil {
+		return true, nil
+	}
+	if err == store.ErrKeyExists || err == store.ErrKeyModified {
+		return false, nil
+	}
+
+	return false, err
+}
+
+//Watch returns a channel of the latest value for key every time it
+//changes. The channel is closed when stopCh is closed or the watch ends.
+func (l *libkvAdapter) Watch(key string, stopCh <-chan struct{}) (<-chan []byte, error) {
+	events, err := l.store.Watch(key, stopCh)
+	if err == store.ErrKeyNotFound {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(chan []byte)
+	go func() {
+		defer close(values)
+		for pair := range events {
+			if pair == nil {
+				continue
+			}
+			values <- pair.Value
+		}
+	}()
+
+	return values, nil
+}
@@ -0,0 +1,24 @@
+package kvstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKVStoreEmptyEndpoint(t *testing.T) {
+	_, err := NewKVStore("")
+	assert.NotNil(t, err)
+}
+
+func TestNewKVStoreUnknownScheme(t *testing.T) {
+	_, err := NewKVStore("bogus://127.0.0.1:1234")
+	assert.NotNil(t, err)
+}
+
+func TestNewKVStoreKnownSchemesRegistered(t *testing.T) {
+	for _, scheme := range []string{"consul", "etcd", "zk", "boltdb"} {
+		_, ok := backendRegistry[scheme]
+		assert.True(t, ok, "expected a backend registered for scheme %s", scheme)
+	}
+}
@@ -0,0 +1,19 @@
+package kvstore
+
+import (
+	"net/url"
+
+	"github.com/docker/libkv/store"
+	"github.com/docker/libkv/store/zookeeper"
+)
+
+func init() {
+	zookeeper.Register()
+	registerBackend("zk", newZKStore)
+}
+
+//newZKStore builds a KVStore backed by a ZooKeeper ensemble at the
+//host:port given in the endpoint URL, e.g. zk://127.0.0.1:2181.
+func newZKStore(endpoint *url.URL) (KVStore, error) {
+	return newLibkvAdapter(store.ZK, endpoint, nil)
+}
@@ -0,0 +1,24 @@
+package loadbalancer
+
+//ownsHealthCheck and publishEndpointStatus let the optional cluster
+//subsystem take over health check ownership and status replication
+//without loadbalancer having to import cluster (which imports
+//loadbalancer for LoadBalancerEndpoint). When no cluster is configured
+//both default to single-node behavior: every node owns every check, and
+//status changes are not published anywhere.
+var (
+	ownsHealthCheck      = func(address string) bool { return true }
+	publishEndpointStatus = func(address string, up bool) {}
+)
+
+//SetClusterHooks is called by cluster.Join to wire gossip-based health
+//check ownership and status replication into the load balancer's health
+//check loop.
+func SetClusterHooks(owns func(address string) bool, publish func(address string, up bool)) {
+	if owns != nil {
+		ownsHealthCheck = owns
+	}
+	if publish != nil {
+		publishEndpointStatus = publish
+	}
+}
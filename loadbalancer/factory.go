@@ -0,0 +1,37 @@
+package loadbalancer
+
+import "fmt"
+
+//DefaultLoadBalancerPolicy is used when a backend's configuration does
+//not specify a LoadBalancerPolicy, preserving the historical behavior of
+//backends built before per-backend policy selection existed.
+const DefaultLoadBalancerPolicy = "round-robin"
+
+var loadBalancerFactories = make(map[string]LoadBalancerFactory)
+
+func init() {
+	RegisterLoadBalancerFactory(DefaultLoadBalancerPolicy, new(RoundRobinLoadBalancerFactory))
+	RegisterLoadBalancerFactory("weighted-round-robin", new(WeightedRoundRobinLoadBalancerFactory))
+	RegisterLoadBalancerFactory("least-connections", new(LeastConnectionsLoadBalancerFactory))
+}
+
+//RegisterLoadBalancerFactory associates a policy name with the factory
+//used to build load balancers for backends configured with that policy.
+func RegisterLoadBalancerFactory(policy string, factory LoadBalancerFactory) {
+	loadBalancerFactories[policy] = factory
+}
+
+//LoadBalancerFactoryForPolicy looks up the factory registered for policy.
+//An empty policy resolves to DefaultLoadBalancerPolicy.
+func LoadBalancerFactoryForPolicy(policy string) (LoadBalancerFactory, error) {
+	if policy == "" {
+		policy = DefaultLoadBalancerPolicy
+	}
+
+	factory, ok := loadBalancerFactories[policy]
+	if !ok {
+		return nil, fmt.Errorf("No load balancer factory registered for policy %s", policy)
+	}
+
+	return factory, nil
+}
@@ -0,0 +1,44 @@
+package loadbalancer
+
+import "sync"
+
+//endpointRegistry maps a connect address to every LoadBalancer instance
+//that has an endpoint at it, so a status transition learned from another
+//node over cluster gossip can be applied to the LoadBalancer(s) actually
+//routing against that address. It is populated as each LoadBalancer is
+//constructed (see registerEndpointAddress) and consulted by
+//ApplyGossipedStatus.
+var (
+	endpointRegistryMutex sync.Mutex
+	endpointRegistry      = make(map[string][]LoadBalancer)
+)
+
+//registerEndpointAddress records that lb has an endpoint at address.
+func registerEndpointAddress(address string, lb LoadBalancer) {
+	endpointRegistryMutex.Lock()
+	defer endpointRegistryMutex.Unlock()
+	endpointRegistry[address] = append(endpointRegistry[address], lb)
+}
+
+//ApplyGossipedStatus applies an up/down transition learned from another
+//node over cluster gossip to every LoadBalancer known to have an endpoint
+//at address. It is the gossip-to-LoadBalancer counterpart to
+//SetClusterHooks' publish hook: publishEndpointStatus carries a
+//locally-observed transition out to the rest of the cluster, and
+//ApplyGossipedStatus carries a transition gossiped in by another node back
+//into this node's own LoadBalancer state, so a failure detected by
+//whichever node owns the check is honored by every node's routing
+//decisions, not just reflected in cluster status output.
+func ApplyGossipedStatus(address string, up bool) {
+	endpointRegistryMutex.Lock()
+	lbs := endpointRegistry[address]
+	endpointRegistryMutex.Unlock()
+
+	for _, lb := range lbs {
+		if up {
+			lb.MarkEndpointUp(address)
+		} else {
+			lb.MarkEndpointDown(address)
+		}
+	}
+}
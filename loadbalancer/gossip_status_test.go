@@ -0,0 +1,47 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	"github.com/xtracdev/xavi/config"
+)
+
+//TestApplyGossipedStatusStopsRoutingToEndpointMarkedDown simulates a
+//non-owner node receiving a gossiped "down" transition for an endpoint it
+//does not itself health check (ownsHealthCheck would be false for it in a
+//real cluster). ApplyGossipedStatus is what gossip.go's NotifyMsg calls
+//with that transition; this proves it actually reaches the LoadBalancer
+//that routes against the endpoint, not just the cluster's status display.
+func TestApplyGossipedStatusStopsRoutingToEndpointMarkedDown(t *testing.T) {
+	factory := &RoundRobinLoadBalancerFactory{}
+	lb, err := factory.NewLoadBalancer("gossip-status-test-backend", "", config.ProtocolHTTP, []config.ServerConfig{
+		{Address: "10.99.0.1", Port: 19001},
+		{Address: "10.99.0.2", Port: 19002},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downAddress := "10.99.0.1:19001"
+	ApplyGossipedStatus(downAddress, false)
+
+	for i := 0; i < 4; i++ {
+		addr, err := lb.GetConnectAddress()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr == downAddress {
+			t.Fatalf("expected routing to avoid %s once gossip marked it down, but it was picked", downAddress)
+		}
+	}
+
+	ApplyGossipedStatus(downAddress, true)
+
+	healthy, unhealthy := lb.GetEndpoints()
+	if len(unhealthy) != 0 {
+		t.Fatalf("expected no unhealthy endpoints once gossip marked %s back up, got %v", downAddress, unhealthy)
+	}
+	if len(healthy) != 2 {
+		t.Fatalf("expected both endpoints healthy again, got %v", healthy)
+	}
+}
@@ -0,0 +1,76 @@
+package loadbalancer
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/armon/go-metrics"
+	"github.com/xtracdev/xavi/config"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+//MakeGRPCHealthCheck builds the goroutine function that repeatedly calls
+//the gRPC Health Checking Protocol's Health/Check RPC against endpoint
+//and updates endpoint.Up accordingly. It is the config.ProtocolGRPC counterpart
+//to MakeHealthCheck, which pings an HTTP URI instead.
+func MakeGRPCHealthCheck(endpoint *LoadBalancerEndpoint, server config.ServerConfig, markInitialUp bool) func() {
+	endpoint.Up = markInitialUp
+
+	interval := server.HealthCheckIntervalSecs
+	if interval <= 0 {
+		interval = defaultHealthCheckIntervalSecs
+	}
+
+	return func() {
+		for {
+			if ownsHealthCheck(endpoint.Address) {
+				up := pingGRPCEndpoint(endpoint)
+				if up != endpoint.Up {
+					endpoint.Up = up
+					if up {
+						metrics.SetGauge([]string{"endpoint", endpoint.Address}, 1.0)
+					} else {
+						metrics.SetGauge([]string{"endpoint", endpoint.Address}, 0.0)
+					}
+					publishEndpointStatus(endpoint.Address, up)
+				}
+			}
+
+			time.Sleep(time.Duration(interval) * time.Second)
+		}
+	}
+}
+
+func pingGRPCEndpoint(endpoint *LoadBalancerEndpoint) bool {
+	dialOpts := []grpc.DialOption{grpc.WithTimeout(5 * time.Second), grpc.WithBlock()}
+	if !endpoint.mTLSEnabled() && endpoint.CACertPath == "" {
+		dialOpts = append(dialOpts, grpc.WithInsecure())
+	} else {
+		creds, err := grpcTransportCredentials(endpoint)
+		if err != nil {
+			log.Warn("gRPC health check TLS error for ", endpoint.Address, ": ", err.Error())
+			return false
+		}
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
+	}
+
+	conn, err := grpc.Dial(endpoint.Address, dialOpts...)
+	if err != nil {
+		log.Warn("gRPC health check failed to dial ", endpoint.Address, ": ", err.Error())
+		return false
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		log.Warn("gRPC health check RPC failed for ", endpoint.Address, ": ", err.Error())
+		return false
+	}
+
+	return resp.Status == grpc_health_v1.HealthCheckResponse_SERVING
+}
@@ -0,0 +1,96 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"google.golang.org/grpc/resolver"
+)
+
+//Scheme is the gRPC resolver scheme xavi registers under, so a gRPC
+//client can dial "xavi:///<backend-name>" and receive the load
+//balancer's healthy endpoints as a grpclb-style server list that updates
+//as endpoints go up and down.
+const Scheme = "xavi"
+
+//pollInterval is how often the resolver re-reads the load balancer's
+//endpoint set and pushes an update to the gRPC client.
+const pollInterval = 5 * time.Second
+
+//resolverRegistry maps a backend name (the resolver target's path) to
+//the LoadBalancer whose GetEndpoints output should be exposed as a
+//server list. RegisterResolverTarget populates it as backends are built.
+var resolverRegistry = make(map[string]LoadBalancer)
+
+//RegisterResolverTarget makes lb's endpoints resolvable by gRPC clients
+//dialing xavi:///backendName.
+func RegisterResolverTarget(backendName string, lb LoadBalancer) {
+	resolverRegistry[backendName] = lb
+}
+
+//grpcResolverBuilder implements resolver.Builder for the xavi scheme.
+type grpcResolverBuilder struct{}
+
+func init() {
+	resolver.Register(&grpcResolverBuilder{})
+}
+
+func (b *grpcResolverBuilder) Scheme() string { return Scheme }
+
+func (b *grpcResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	lb, ok := resolverRegistry[target.Endpoint]
+	if !ok {
+		return nil, fmt.Errorf("no backend registered for gRPC resolver target %s", target.Endpoint)
+	}
+
+	r := &grpcResolver{lb: lb, cc: cc, stop: make(chan struct{})}
+	r.update()
+	go r.watch()
+
+	return r, nil
+}
+
+//grpcResolver is a resolver.Resolver that pushes the wrapped
+//LoadBalancer's healthy endpoints to a gRPC ClientConn, polling for
+//changes since LoadBalancer has no native subscribe primitive.
+type grpcResolver struct {
+	lb   LoadBalancer
+	cc   resolver.ClientConn
+	stop chan struct{}
+}
+
+func (r *grpcResolver) watch() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.update()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func (r *grpcResolver) update() {
+	healthy, _ := r.lb.GetEndpoints()
+
+	addresses := make([]resolver.Address, 0, len(healthy))
+	for _, addr := range healthy {
+		addresses = append(addresses, resolver.Address{Addr: addr})
+	}
+
+	if err := r.cc.UpdateState(resolver.State{Addresses: addresses}); err != nil {
+		log.Warn("error updating gRPC resolver state: ", err.Error())
+	}
+}
+
+func (r *grpcResolver) ResolveNow(resolver.ResolveNowOptions) {
+	r.update()
+}
+
+func (r *grpcResolver) Close() {
+	close(r.stop)
+}
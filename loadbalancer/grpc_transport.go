@@ -0,0 +1,40 @@
+package loadbalancer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"google.golang.org/grpc/credentials"
+)
+
+//grpcTransportCredentials builds gRPC transport credentials from the same
+//CA/client certificate paths BuildTransport uses for HTTP backends, so an
+//endpoint's mTLS identity is shared between its HTTP and gRPC traffic.
+func grpcTransportCredentials(endpoint *LoadBalancerEndpoint) (credentials.TransportCredentials, error) {
+	tlsConfig := &tls.Config{}
+
+	if endpoint.CACertPath != "" {
+		caCert, err := ioutil.ReadFile(endpoint.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA cert for %s: %s", endpoint.Address, err.Error())
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in CA cert %s", endpoint.CACertPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if endpoint.mTLSEnabled() {
+		cert, err := tls.LoadX509KeyPair(endpoint.ClientCertPath, endpoint.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client cert for %s: %s", endpoint.Address, err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
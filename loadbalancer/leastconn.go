@@ -0,0 +1,173 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/armon/go-metrics"
+	"github.com/xtracdev/xavi/config"
+)
+
+//connCountedServer pairs an endpoint with the number of requests
+//currently in flight against it.
+type connCountedServer struct {
+	endpoint *LoadBalancerEndpoint
+	weight   int
+	inFlight int64
+}
+
+//LeastConnectionsLoadBalancer hands out the healthy endpoint with the
+//fewest requests currently in flight, breaking ties by weight.
+type LeastConnectionsLoadBalancer struct {
+	backend string
+
+	mutex   sync.RWMutex
+	servers []*connCountedServer
+}
+
+//LeastConnectionsLoadBalancerFactory is the method receiver for the
+//least connections load balancer factory method.
+type LeastConnectionsLoadBalancerFactory struct{}
+
+//NewLoadBalancer creates a new instance of a least connections load
+//balancer.
+func (lcf *LeastConnectionsLoadBalancerFactory) NewLoadBalancer(backendName, caCertPath, protocol string, servers []config.ServerConfig) (LoadBalancer, error) {
+	if backendName == "" {
+		return nil, fmt.Errorf("Expected non-empty backend name")
+	}
+
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("Expected at least one server in servers argument")
+	}
+
+	lc := &LeastConnectionsLoadBalancer{backend: backendName}
+
+	makeHealthCheck := healthCheckBuilderFor(protocol)
+
+	for _, s := range servers {
+		weight := s.Weight
+		if weight <= 0 {
+			weight = defaultWeight
+		}
+
+		lbEndpoint := &LoadBalancerEndpoint{
+			Address:        fmt.Sprintf("%s:%d", s.Address, s.Port),
+			PingURI:        s.PingURI,
+			CACertPath:     caCertPath,
+			Weight:         weight,
+			ClientCertPath: s.ClientCertPath,
+			ClientKeyPath:  s.ClientKeyPath,
+		}
+		metrics.SetGauge([]string{"endpoint", lbEndpoint.Address}, 1.0)
+
+		log.Info("Spawning health check for address ", lbEndpoint.Address)
+		healthCheckFunction := makeHealthCheck(lbEndpoint, s, true)
+		go healthCheckFunction()
+
+		log.Info("Adding least-connections server with address ", lbEndpoint.Address)
+		lc.servers = append(lc.servers, &connCountedServer{endpoint: lbEndpoint, weight: weight})
+
+		registerEndpointAddress(lbEndpoint.Address, lc)
+	}
+
+	return lc, nil
+}
+
+//GetConnectAddress picks the healthy endpoint with the fewest requests
+//currently in flight, breaking ties in favor of the higher-weight
+//server, and increments its in-flight counter. Callers must invoke
+//ReleaseConnection with the same address, typically in a defer, once the
+//request completes.
+func (lc *LeastConnectionsLoadBalancer) GetConnectAddress() (string, error) {
+	lc.mutex.RLock()
+	defer lc.mutex.RUnlock()
+
+	var winner *connCountedServer
+
+	for _, s := range lc.servers {
+		if !s.endpoint.IsUp() {
+			continue
+		}
+
+		if winner == nil {
+			winner = s
+			continue
+		}
+
+		inFlight, winnerInFlight := atomic.LoadInt64(&s.inFlight), atomic.LoadInt64(&winner.inFlight)
+		if inFlight < winnerInFlight || (inFlight == winnerInFlight && s.weight > winner.weight) {
+			winner = s
+		}
+	}
+
+	if winner == nil {
+		return "", fmt.Errorf("All servers in backend %s are marked down", lc.backend)
+	}
+
+	atomic.AddInt64(&winner.inFlight, 1)
+
+	return winner.endpoint.Address, nil
+}
+
+//ReleaseConnection decrements the in-flight counter for connectAddress.
+//Call it once the request dispatched to that address has completed.
+func (lc *LeastConnectionsLoadBalancer) ReleaseConnection(connectAddress string) {
+	lc.mutex.RLock()
+	defer lc.mutex.RUnlock()
+
+	for _, s := range lc.servers {
+		if s.endpoint.Address == connectAddress {
+			atomic.AddInt64(&s.inFlight, -1)
+			return
+		}
+	}
+}
+
+//MarkEndpointUp marks the endpoint associated with the connect address as up.
+func (lc *LeastConnectionsLoadBalancer) MarkEndpointUp(connectAddress string) error {
+	return lc.changeEndpointStatus(connectAddress, true)
+}
+
+//MarkEndpointDown marks the endpoint associated with the connect address as down.
+func (lc *LeastConnectionsLoadBalancer) MarkEndpointDown(connectAddress string) error {
+	return lc.changeEndpointStatus(connectAddress, false)
+}
+
+func (lc *LeastConnectionsLoadBalancer) changeEndpointStatus(connectAddress string, status bool) error {
+	lc.mutex.RLock()
+	defer lc.mutex.RUnlock()
+
+	for _, s := range lc.servers {
+		if s.endpoint.Address == connectAddress {
+			s.endpoint.Up = status
+			if status {
+				metrics.SetGauge([]string{"endpoint", connectAddress}, 1.0)
+			} else {
+				metrics.SetGauge([]string{"endpoint", connectAddress}, 0.0)
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Address not found in load balancing pool: %s", connectAddress)
+}
+
+//GetEndpoints returns the endpoints associated with the load balancer,
+//partitioning the set of endpoints into healthy and unhealthy endpoints.
+func (lc *LeastConnectionsLoadBalancer) GetEndpoints() ([]string, []string) {
+	lc.mutex.RLock()
+	defer lc.mutex.RUnlock()
+
+	var healthy, unhealthy []string
+	for _, s := range lc.servers {
+		if s.endpoint.IsUp() {
+			healthy = append(healthy, s.endpoint.Address)
+		} else {
+			unhealthy = append(unhealthy, s.endpoint.Address)
+		}
+	}
+
+	return healthy, unhealthy
+}
@@ -0,0 +1,84 @@
+package loadbalancer
+
+import (
+	"testing"
+)
+
+func TestLeastConnectionsTieBreakByWeight(t *testing.T) {
+	a := &LoadBalancerEndpoint{Address: "a:1", Up: true}
+	b := &LoadBalancerEndpoint{Address: "b:1", Up: true}
+
+	lc := &LeastConnectionsLoadBalancer{
+		backend: "test",
+		servers: []*connCountedServer{
+			{endpoint: a, weight: 1},
+			{endpoint: b, weight: 5},
+		},
+	}
+
+	addr, err := lc.GetConnectAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr != "b:1" {
+		t.Fatalf("expected tie on in-flight count to be broken in favor of the higher-weight server b:1, got %s", addr)
+	}
+}
+
+func TestLeastConnectionsPicksFewestInFlight(t *testing.T) {
+	a := &LoadBalancerEndpoint{Address: "a:1", Up: true}
+	b := &LoadBalancerEndpoint{Address: "b:1", Up: true}
+
+	lc := &LeastConnectionsLoadBalancer{
+		backend: "test",
+		servers: []*connCountedServer{
+			{endpoint: a, weight: 1, inFlight: 3},
+			{endpoint: b, weight: 1, inFlight: 1},
+		},
+	}
+
+	addr, err := lc.GetConnectAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr != "b:1" {
+		t.Fatalf("expected the server with fewer in-flight requests b:1 to be picked, got %s", addr)
+	}
+}
+
+func TestLeastConnectionsSkipsDownServers(t *testing.T) {
+	a := &LoadBalancerEndpoint{Address: "a:1", Up: false}
+	b := &LoadBalancerEndpoint{Address: "b:1", Up: true}
+
+	lc := &LeastConnectionsLoadBalancer{
+		backend: "test",
+		servers: []*connCountedServer{
+			{endpoint: a, weight: 1},
+			{endpoint: b, weight: 1, inFlight: 10},
+		},
+	}
+
+	addr, err := lc.GetConnectAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr != "b:1" {
+		t.Fatalf("expected the down server a:1 to be skipped, got %s", addr)
+	}
+}
+
+func TestLeastConnectionsAllDown(t *testing.T) {
+	a := &LoadBalancerEndpoint{Address: "a:1", Up: false}
+
+	lc := &LeastConnectionsLoadBalancer{
+		backend: "test",
+		servers: []*connCountedServer{{endpoint: a, weight: 1}},
+	}
+
+	if _, err := lc.GetConnectAddress(); err == nil {
+		t.Fatal("expected an error when every server is down")
+	}
+}
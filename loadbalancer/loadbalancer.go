@@ -0,0 +1,163 @@
+//Package loadbalancer selects which backend endpoint a request is sent to
+//and tracks the up/down health of each endpoint in a backend's pool.
+package loadbalancer
+
+import (
+	"net/http"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/armon/go-metrics"
+	"github.com/xtracdev/xavi/config"
+)
+
+//LoadBalancer is the interface implemented by every load balancing
+//strategy (round robin, weighted round robin, least connections, ...).
+type LoadBalancer interface {
+	GetConnectAddress() (string, error)
+	MarkEndpointUp(connectAddress string) error
+	MarkEndpointDown(connectAddress string) error
+	GetEndpoints() (healthy []string, unhealthy []string)
+}
+
+//ConnectionReleaser is implemented by load balancer strategies that track
+//a per-endpoint in-flight request count as part of picking a connect
+//address (currently only LeastConnectionsLoadBalancer). Callers that
+//dispatch a request to an address returned by such a LoadBalancer's
+//GetConnectAddress must type-assert for this interface and call
+//ReleaseConnection once the request completes, or the in-flight count
+//only ever grows.
+type ConnectionReleaser interface {
+	ReleaseConnection(connectAddress string)
+}
+
+//LoadBalancerFactory builds a LoadBalancer for a backend's configured
+//server pool. protocol is one of config.ProtocolHTTP/config.ProtocolGRPC
+//and selects which health check implementation each endpoint is given.
+type LoadBalancerFactory interface {
+	NewLoadBalancer(backendName, caCertPath, protocol string, servers []config.ServerConfig) (LoadBalancer, error)
+}
+
+//EndpointSource records whether an endpoint's up/down status was
+//determined by this node's own health check or learned via cluster
+//gossip from whichever node owns the check for that endpoint.
+type EndpointSource int
+
+const (
+	//SourceLocal means this node runs the health check for the endpoint.
+	SourceLocal EndpointSource = iota
+	//SourceGossiped means this node learned the endpoint's status from
+	//another node over the cluster gossip protocol.
+	SourceGossiped
+)
+
+func (s EndpointSource) String() string {
+	switch s {
+	case SourceGossiped:
+		return "gossiped"
+	default:
+		return "local"
+	}
+}
+
+//LoadBalancerEndpoint is a single backend server as tracked by a load
+//balancer, along with its current health status.
+type LoadBalancerEndpoint struct {
+	Address    string
+	PingURI    string
+	Up         bool
+	CACertPath string
+	Weight     int
+	Source     EndpointSource
+
+	//ClientCertPath and ClientKeyPath, when both set, identify the PEM
+	//files for the client certificate xavi presents to this endpoint for
+	//mutual TLS. They are populated by the optional pki subsystem and
+	//rotated in place as certificates are reissued.
+	ClientCertPath string
+	ClientKeyPath  string
+}
+
+//mTLSEnabled reports whether this endpoint has been given a client
+//identity to use for mutual TLS.
+func (e *LoadBalancerEndpoint) mTLSEnabled() bool {
+	return e.ClientCertPath != "" && e.ClientKeyPath != ""
+}
+
+//IsUp returns true if the endpoint is currently considered healthy.
+func (e *LoadBalancerEndpoint) IsUp() bool {
+	return e.Up
+}
+
+const defaultHealthCheckIntervalSecs = 10
+
+//healthCheckBuilderFor returns MakeHealthCheck for HTTP backends and
+//MakeGRPCHealthCheck for gRPC backends, so each LoadBalancerFactory can
+//spawn the health check appropriate to the backend's protocol without
+//having to know the details of either implementation.
+func healthCheckBuilderFor(protocol string) func(*LoadBalancerEndpoint, config.ServerConfig, bool) func() {
+	if protocol == config.ProtocolGRPC {
+		return MakeGRPCHealthCheck
+	}
+	return MakeHealthCheck
+}
+
+//MakeHealthCheck builds the goroutine function that repeatedly pings
+//endpoint's PingURI and updates endpoint.Up accordingly. When
+//markInitialUp is true the endpoint starts in the up state, giving it a
+//chance to pass its first check before being taken out of rotation.
+func MakeHealthCheck(endpoint *LoadBalancerEndpoint, server config.ServerConfig, markInitialUp bool) func() {
+	endpoint.Up = markInitialUp
+
+	interval := server.HealthCheckIntervalSecs
+	if interval <= 0 {
+		interval = defaultHealthCheckIntervalSecs
+	}
+
+	return func() {
+		for {
+			if ownsHealthCheck(endpoint.Address) {
+				up := pingEndpoint(endpoint)
+				if up != endpoint.Up {
+					endpoint.Up = up
+					if up {
+						metrics.SetGauge([]string{"endpoint", endpoint.Address}, 1.0)
+					} else {
+						metrics.SetGauge([]string{"endpoint", endpoint.Address}, 0.0)
+					}
+					publishEndpointStatus(endpoint.Address, up)
+				}
+			}
+
+			time.Sleep(time.Duration(interval) * time.Second)
+		}
+	}
+}
+
+//pingEndpoint issues the health check GET against the endpoint's PingURI.
+//When the endpoint has been given a client identity by the pki
+//subsystem the ping goes out over the same mTLS transport used for
+//regular traffic, so a certificate problem shows up as a health check
+//failure rather than a confusing runtime error on the proxied path.
+func pingEndpoint(endpoint *LoadBalancerEndpoint) bool {
+	if endpoint.PingURI == "" {
+		return true
+	}
+
+	transport, err := BuildTransport(endpoint)
+	if err != nil {
+		log.Warn("health check transport error for ", endpoint.Address, ": ", err.Error())
+		return false
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second, Transport: transport}
+
+	resp, err := client.Get(endpoint.PingURI)
+	if err != nil {
+		log.Warn("health check failed for ", endpoint.Address, ": ", err.Error())
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
@@ -27,7 +27,7 @@ type RoundRobinLoadBalancer struct {
 type RoundRobinLoadBalancerFactory struct{}
 
 //NewLoadBalancer creates a new instance of a Round Robin load balancer
-func (rrf *RoundRobinLoadBalancerFactory) NewLoadBalancer(backendName, caCertPath string, servers []config.ServerConfig) (LoadBalancer, error) {
+func (rrf *RoundRobinLoadBalancerFactory) NewLoadBalancer(backendName, caCertPath, protocol string, servers []config.ServerConfig) (LoadBalancer, error) {
 	var rrlb RoundRobinLoadBalancer
 
 	if backendName == "" {
@@ -41,6 +41,8 @@ func (rrf *RoundRobinLoadBalancerFactory) NewLoadBalancer(backendName, caCertPat
 	rrlb.backend = backendName
 	rrlb.servers = ring.New(len(servers))
 
+	makeHealthCheck := healthCheckBuilderFor(protocol)
+
 	for _, s := range servers {
 
 		lbEndpoint := new(LoadBalancerEndpoint)
@@ -49,14 +51,18 @@ func (rrf *RoundRobinLoadBalancerFactory) NewLoadBalancer(backendName, caCertPat
 		lbEndpoint.PingURI = s.PingURI
 		lbEndpoint.Up = true
 		lbEndpoint.CACertPath = caCertPath
+		lbEndpoint.ClientCertPath = s.ClientCertPath
+		lbEndpoint.ClientKeyPath = s.ClientKeyPath
 
 		log.Info("Spawing health check for address ", lbEndpoint.Address)
-		healthCheckFunction := MakeHealthCheck(lbEndpoint, s, true)
+		healthCheckFunction := makeHealthCheck(lbEndpoint, s, true)
 		go healthCheckFunction()
 
 		log.Info("Adding server with address ", lbEndpoint.Address)
 		rrlb.servers.Value = lbEndpoint
 		rrlb.servers = rrlb.servers.Next()
+
+		registerEndpointAddress(lbEndpoint.Address, &rrlb)
 	}
 
 	return &rrlb, nil
@@ -0,0 +1,47 @@
+package loadbalancer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+//BuildTransport returns an *http.Transport for dialing endpoint. If
+//endpoint has a CACertPath, the transport's TLS configuration trusts that
+//CA; if it also has a client certificate (ClientCertPath/ClientKeyPath)
+//configured by the pki subsystem, it presents that identity for mutual
+//TLS. Only when neither is set is it a plain transport with no TLS
+//configuration, preserving plaintext behavior for deployments that
+//haven't opted into mTLS.
+func BuildTransport(endpoint *LoadBalancerEndpoint) (*http.Transport, error) {
+	if endpoint.CACertPath == "" && !endpoint.mTLSEnabled() {
+		return &http.Transport{}, nil
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if endpoint.CACertPath != "" {
+		caCert, err := ioutil.ReadFile(endpoint.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA cert for %s: %s", endpoint.Address, err.Error())
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in CA cert %s", endpoint.CACertPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if endpoint.mTLSEnabled() {
+		cert, err := tls.LoadX509KeyPair(endpoint.ClientCertPath, endpoint.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client cert for %s: %s", endpoint.Address, err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Transport{TLSClientConfig: tlsConfig}, nil
+}
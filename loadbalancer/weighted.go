@@ -0,0 +1,162 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/armon/go-metrics"
+	"github.com/xtracdev/xavi/config"
+)
+
+//defaultWeight is used for servers configured with a Weight of zero so an
+//operator who hasn't thought about weights yet still gets even
+//distribution.
+const defaultWeight = 1
+
+//weightedServer tracks one server's static weight alongside the
+//currentWeight the smooth weighted round-robin algorithm mutates on
+//every pick.
+type weightedServer struct {
+	endpoint      *LoadBalancerEndpoint
+	weight        int
+	currentWeight int
+}
+
+//WeightedRoundRobinLoadBalancer hands out connect addresses using smooth
+//weighted round-robin: servers with a higher weight are picked more
+//often, but interleaved evenly rather than in bursty runs.
+type WeightedRoundRobinLoadBalancer struct {
+	backend string
+
+	mutex   sync.Mutex
+	servers []*weightedServer
+}
+
+//WeightedRoundRobinLoadBalancerFactory is the method receiver for the
+//weighted round robin load balancer factory method.
+type WeightedRoundRobinLoadBalancerFactory struct{}
+
+//NewLoadBalancer creates a new instance of a weighted round robin load
+//balancer.
+func (wrrf *WeightedRoundRobinLoadBalancerFactory) NewLoadBalancer(backendName, caCertPath, protocol string, servers []config.ServerConfig) (LoadBalancer, error) {
+	if backendName == "" {
+		return nil, fmt.Errorf("Expected non-empty backend name")
+	}
+
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("Expected at least one server in servers argument")
+	}
+
+	wrr := &WeightedRoundRobinLoadBalancer{backend: backendName}
+
+	makeHealthCheck := healthCheckBuilderFor(protocol)
+
+	for _, s := range servers {
+		weight := s.Weight
+		if weight <= 0 {
+			weight = defaultWeight
+		}
+
+		lbEndpoint := &LoadBalancerEndpoint{
+			Address:        fmt.Sprintf("%s:%d", s.Address, s.Port),
+			PingURI:        s.PingURI,
+			CACertPath:     caCertPath,
+			Weight:         weight,
+			ClientCertPath: s.ClientCertPath,
+			ClientKeyPath:  s.ClientKeyPath,
+		}
+		metrics.SetGauge([]string{"endpoint", lbEndpoint.Address}, 1.0)
+
+		log.Info("Spawning health check for address ", lbEndpoint.Address)
+		healthCheckFunction := makeHealthCheck(lbEndpoint, s, true)
+		go healthCheckFunction()
+
+		log.Info("Adding weighted server with address ", lbEndpoint.Address, " weight ", weight)
+		wrr.servers = append(wrr.servers, &weightedServer{endpoint: lbEndpoint, weight: weight})
+
+		registerEndpointAddress(lbEndpoint.Address, wrr)
+	}
+
+	return wrr, nil
+}
+
+//GetConnectAddress picks the next address using the standard smooth
+//weighted round-robin algorithm: every healthy server's currentWeight is
+//bumped by its static weight, the highest currentWeight wins, and the
+//sum of all weights is subtracted from the winner.
+func (wrr *WeightedRoundRobinLoadBalancer) GetConnectAddress() (string, error) {
+	wrr.mutex.Lock()
+	defer wrr.mutex.Unlock()
+
+	var totalWeight int
+	var winner *weightedServer
+
+	for _, s := range wrr.servers {
+		if !s.endpoint.IsUp() {
+			continue
+		}
+
+		totalWeight += s.weight
+		s.currentWeight += s.weight
+
+		if winner == nil || s.currentWeight > winner.currentWeight {
+			winner = s
+		}
+	}
+
+	if winner == nil {
+		return "", fmt.Errorf("All servers in backend %s are marked down", wrr.backend)
+	}
+
+	winner.currentWeight -= totalWeight
+
+	return winner.endpoint.Address, nil
+}
+
+//MarkEndpointUp marks the endpoint associated with the connect address as up.
+func (wrr *WeightedRoundRobinLoadBalancer) MarkEndpointUp(connectAddress string) error {
+	return wrr.changeEndpointStatus(connectAddress, true)
+}
+
+//MarkEndpointDown marks the endpoint associated with the connect address as down.
+func (wrr *WeightedRoundRobinLoadBalancer) MarkEndpointDown(connectAddress string) error {
+	return wrr.changeEndpointStatus(connectAddress, false)
+}
+
+func (wrr *WeightedRoundRobinLoadBalancer) changeEndpointStatus(connectAddress string, status bool) error {
+	wrr.mutex.Lock()
+	defer wrr.mutex.Unlock()
+
+	for _, s := range wrr.servers {
+		if s.endpoint.Address == connectAddress {
+			s.endpoint.Up = status
+			if status {
+				metrics.SetGauge([]string{"endpoint", connectAddress}, 1.0)
+			} else {
+				metrics.SetGauge([]string{"endpoint", connectAddress}, 0.0)
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Address not found in load balancing pool: %s", connectAddress)
+}
+
+//GetEndpoints returns the endpoints associated with the load balancer,
+//partitioning the set of endpoints into healthy and unhealthy endpoints.
+func (wrr *WeightedRoundRobinLoadBalancer) GetEndpoints() ([]string, []string) {
+	wrr.mutex.Lock()
+	defer wrr.mutex.Unlock()
+
+	var healthy, unhealthy []string
+	for _, s := range wrr.servers {
+		if s.endpoint.IsUp() {
+			healthy = append(healthy, s.endpoint.Address)
+		} else {
+			unhealthy = append(unhealthy, s.endpoint.Address)
+		}
+	}
+
+	return healthy, unhealthy
+}
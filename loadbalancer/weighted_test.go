@@ -0,0 +1,44 @@
+package loadbalancer
+
+import (
+	"testing"
+)
+
+func TestWeightedRoundRobinInterleaving(t *testing.T) {
+	a := &LoadBalancerEndpoint{Address: "a:1", Up: true}
+	b := &LoadBalancerEndpoint{Address: "b:1", Up: true}
+
+	wrr := &WeightedRoundRobinLoadBalancer{
+		backend: "test",
+		servers: []*weightedServer{
+			{endpoint: a, weight: 5},
+			{endpoint: b, weight: 1},
+		},
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		addr, err := wrr.GetConnectAddress()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[addr]++
+	}
+
+	if counts["a:1"] != 5 || counts["b:1"] != 1 {
+		t.Fatalf("expected a:1 picked 5 times and b:1 picked 1 time over 6 picks, got %v", counts)
+	}
+}
+
+func TestWeightedRoundRobinAllDown(t *testing.T) {
+	a := &LoadBalancerEndpoint{Address: "a:1", Up: false}
+
+	wrr := &WeightedRoundRobinLoadBalancer{
+		backend: "test",
+		servers: []*weightedServer{{endpoint: a, weight: 1}},
+	}
+
+	if _, err := wrr.GetConnectAddress(); err == nil {
+		t.Fatal("expected an error when every server is down")
+	}
+}
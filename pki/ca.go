@@ -0,0 +1,200 @@
+//Package pki provisions and rotates the RSA root CA xavi uses to issue
+//per-listener server certificates and per-backend client certificates,
+//so a cluster can run with mutual TLS between xavi and its backends
+//without an operator having to bring their own PKI. It is optional:
+//deployments that never call Bootstrap keep running in plaintext exactly
+//as before.
+package pki
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/xavi/kvstore"
+)
+
+const (
+	//caKey is the single KV entry the CA's cert and key are stored under
+	//as one JSON document, so Bootstrap can create it with one AtomicPut
+	//instead of racing two separate puts that could each be won by a
+	//different node.
+	caKey = "/xavi/pki/ca"
+
+	caKeyBits  = 4096
+	caValidity = 10 * 365 * 24 * time.Hour
+)
+
+//caRecord is the JSON-serialized form of a CA stored in kvs.
+type caRecord struct {
+	CertPEM []byte `json:"cert_pem"`
+	KeyPEM  []byte `json:"key_pem"`
+}
+
+//CA is the root certificate authority used to sign every server and
+//client certificate xavi issues.
+type CA struct {
+	Cert    *x509.Certificate
+	CertPEM []byte
+	Key     *rsa.PrivateKey
+	KeyPEM  []byte
+}
+
+//Bootstrap loads the cluster's root CA from kvs, generating and storing a
+//new one on first start. It is safe to call from every node at startup:
+//each generates its own candidate CA, but only the one that wins the
+//AtomicPut race is ever used - every other node discards the CA it
+//generated and loads the winner instead, so the cluster never ends up
+//with nodes trusting divergent CAs.
+func Bootstrap(kvs kvstore.KVStore) (*CA, error) {
+	ca, err := Load(kvs)
+	if err == nil {
+		return ca, nil
+	}
+	if err != kvstore.ErrKeyNotFound {
+		return nil, err
+	}
+
+	log.Info("No existing PKI root CA found - generating one")
+	ca, err = generateCA()
+	if err != nil {
+		return nil, err
+	}
+
+	won, err := save(kvs, ca)
+	if err != nil {
+		return nil, err
+	}
+	if won {
+		return ca, nil
+	}
+
+	log.Info("Lost the race to bootstrap the PKI root CA - loading the winner")
+	return Load(kvs)
+}
+
+//Load reads the root CA's certificate and key back out of kvs.
+func Load(kvs kvstore.KVStore) (*CA, error) {
+	raw, err := kvs.Get(caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var record caRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return nil, fmt.Errorf("error decoding stored CA record: %s", err.Error())
+	}
+
+	return decodeCA(record.CertPEM, record.KeyPEM)
+}
+
+//save writes ca to kvs as a single atomic entry, so a concurrent
+//Bootstrap on another node either sees the whole CA or none of it.
+func save(kvs kvstore.KVStore, ca *CA) (bool, error) {
+	raw, err := json.Marshal(caRecord{CertPEM: ca.CertPEM, KeyPEM: ca.KeyPEM})
+	if err != nil {
+		return false, err
+	}
+
+	return kvs.AtomicPut(caKey, raw)
+}
+
+//forceSave unconditionally overwrites the stored CA, for Rotate where
+//replacing the existing CA is the intent rather than a race to bear.
+func forceSave(kvs kvstore.KVStore, ca *CA) error {
+	raw, err := json.Marshal(caRecord{CertPEM: ca.CertPEM, KeyPEM: ca.KeyPEM})
+	if err != nil {
+		return err
+	}
+
+	return kvs.Put(caKey, raw)
+}
+
+//ExportCert writes the CA's certificate (not its private key) to path.
+func (ca *CA) ExportCert(path string) error {
+	return ioutil.WriteFile(path, ca.CertPEM, 0644)
+}
+
+//Rotate replaces the cluster root CA in kvs with a newly generated one.
+//Leaf certificates already issued remain valid under the old CA until
+//their own expiry; callers that want every identity reissued under the
+//new CA should follow Rotate with a fresh RotateClientCert cycle.
+func Rotate(kvs kvstore.KVStore) error {
+	ca, err := generateCA()
+	if err != nil {
+		return err
+	}
+
+	return forceSave(kvs, ca)
+}
+
+func generateCA() (*CA, error) {
+	key, err := rsa.GenerateKey(rand.Reader, caKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("error generating CA key: %s", err.Error())
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "xavi root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating CA certificate: %s", err.Error())
+	}
+
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{
+		Cert:    cert,
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		Key:     key,
+		KeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}, nil
+}
+
+func decodeCA(certPEM, keyPEM []byte) (*CA, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, fmt.Errorf("invalid CA certificate PEM")
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("invalid CA key PEM")
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{Cert: cert, CertPEM: certPEM, Key: key, KeyPEM: keyPEM}, nil
+}
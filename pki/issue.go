@@ -0,0 +1,73 @@
+package pki
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	leafKeyBits  = 2048
+	leafValidity = 90 * 24 * time.Hour
+)
+
+//Certificate is a leaf certificate issued and signed by the cluster CA,
+//along with its validity so callers know when to ask for a fresh one.
+type Certificate struct {
+	CertPEM  []byte
+	KeyPEM   []byte
+	NotAfter time.Time
+}
+
+//IssueServerCert issues a server certificate for a listener, valid for
+//the given DNS name/address, signed by ca.
+func (ca *CA) IssueServerCert(commonName string) (*Certificate, error) {
+	return ca.issue(commonName, x509.ExtKeyUsageServerAuth)
+}
+
+//IssueClientCert issues a client certificate identifying xavi to a
+//backend for mTLS, signed by ca.
+func (ca *CA) IssueClientCert(commonName string) (*Certificate, error) {
+	return ca.issue(commonName, x509.ExtKeyUsageClientAuth)
+}
+
+func (ca *CA) issue(commonName string, extKeyUsage x509.ExtKeyUsage) (*Certificate, error) {
+	key, err := rsa.GenerateKey(rand.Reader, leafKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("error generating leaf key for %s: %s", commonName, err.Error())
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := time.Now().Add(leafValidity)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{extKeyUsage},
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, &key.PublicKey, ca.Key)
+	if err != nil {
+		return nil, fmt.Errorf("error issuing certificate for %s: %s", commonName, err.Error())
+	}
+
+	return &Certificate{
+		CertPEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		KeyPEM:   pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		NotAfter: notAfter,
+	}, nil
+}
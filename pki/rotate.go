@@ -0,0 +1,78 @@
+package pki
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//rotateBeforeExpiry is how far ahead of a leaf certificate's expiry
+//RotateClientCert reissues it, so there's no window where the old cert
+//has expired but the new one isn't live yet.
+const rotateBeforeExpiry = 24 * time.Hour
+
+//WriteToDisk PEM-encodes cert and key to certPath/keyPath so they can be
+//handed to an *http.Transport or http.Server via standard library TLS
+//configuration.
+func (c *Certificate) WriteToDisk(certPath, keyPath string) error {
+	if err := ioutil.WriteFile(certPath, c.CertPEM, 0600); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(keyPath, c.KeyPEM, 0600)
+}
+
+//RotateClientCert issues a client certificate for commonName, writes it
+//to certDir, and reissues/rewrites it every interval (or immediately if
+//the current cert is within rotateBeforeExpiry of expiring), invoking
+//onRotate with the new cert/key paths after each write so callers can
+//reload their TLS configuration.
+func (ca *CA) RotateClientCert(commonName, certDir string, interval time.Duration, onRotate func(certPath, keyPath string)) (stop chan struct{}, err error) {
+	certPath := filepath.Join(certDir, commonName+"-cert.pem")
+	keyPath := filepath.Join(certDir, commonName+"-key.pem")
+
+	issueAndWrite := func() (time.Time, error) {
+		cert, err := ca.IssueClientCert(commonName)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if err := cert.WriteToDisk(certPath, keyPath); err != nil {
+			return time.Time{}, err
+		}
+		onRotate(certPath, keyPath)
+		return cert.NotAfter, nil
+	}
+
+	notAfter, err := issueAndWrite()
+	if err != nil {
+		return nil, err
+	}
+
+	stop = make(chan struct{})
+	go func() {
+		for {
+			wait := interval
+			if untilExpiry := time.Until(notAfter) - rotateBeforeExpiry; untilExpiry < wait {
+				wait = untilExpiry
+			}
+			if wait < 0 {
+				wait = 0
+			}
+
+			select {
+			case <-time.After(wait):
+				na, err := issueAndWrite()
+				if err != nil {
+					log.Error("error rotating client cert for ", commonName, ": ", err.Error())
+					continue
+				}
+				notAfter = na
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return stop, nil
+}
@@ -3,6 +3,7 @@ package runner
 import (
 	"fmt"
 	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/xavi/cluster"
 	"github.com/xtracdev/xavi/env"
 	"github.com/xtracdev/xavi/kvstore"
 	"github.com/xtracdev/xavi/shell"
@@ -63,6 +64,10 @@ func setupXAVIEnvironment(pluginRegistrationFn func()) kvstore.KVStore {
 		log.Fatal(err.Error())
 	}
 
+	if _, err := cluster.Join(); err != nil {
+		log.Warn("error joining cluster, running in single-node mode: ", err.Error())
+	}
+
 	return kvs
 }
 
@@ -40,10 +40,14 @@ func BuildServiceForListener(name string, address string, kvs kvstore.KVStore) (
 		if err != nil {
 			return nil, err
 		}
-		managedService.AddRoute(route)
-		if err != nil {
-			return nil, err
+
+		protocol := route.Backend.Protocol
+		if protocol == "" {
+			protocol = config.ProtocolHTTP
 		}
+		log.Info("route "+routeName+" backed by "+route.Backend.Name+" over protocol ", protocol)
+
+		managedService.AddRoute(route)
 	}
 
 	return managedService, nil
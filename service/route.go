@@ -0,0 +1,262 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/xtracdev/xavi/config"
+	"github.com/xtracdev/xavi/kvstore"
+	"github.com/xtracdev/xavi/loadbalancer"
+	"github.com/xtracdev/xavi/pki"
+	"github.com/xtracdev/xavi/timer"
+)
+
+//clientCertRotateInterval is how often RotateClientCert reissues the
+//client certificate xavi presents to a backend's servers.
+const clientCertRotateInterval = 24 * time.Hour
+
+var (
+	caMutex    sync.Mutex
+	cachedCA   *pki.CA
+	cachedCert string
+)
+
+//Route is a single mount point within a listener: requests whose path
+//matches URIRoot are dispatched to Handler, which is backed by a load
+//balancer over the route's backend servers.
+type Route struct {
+	Name         string
+	URIRoot      string
+	Backend      config.BackendConfig
+	LoadBalancer loadbalancer.LoadBalancer
+	Handler      http.Handler
+}
+
+//buildRoute reads the named route's definition, resolves its backend's
+//server pool, and builds the load balancer and protocol-appropriate
+//proxy handler (HTTP or gRPC, per backend.Protocol) that requests
+//matching the route are dispatched to.
+func buildRoute(name string, kvs kvstore.KVStore) (*Route, error) {
+	routeConfig, err := config.ReadRouteConfig(name, kvs)
+	if err != nil {
+		return nil, fmt.Errorf("error reading route config for %s: %s", name, err.Error())
+	}
+
+	backendConfig, err := config.ReadBackendConfig(routeConfig.BackendName, kvs)
+	if err != nil {
+		return nil, fmt.Errorf("error reading backend config for %s: %s", routeConfig.BackendName, err.Error())
+	}
+
+	servers, err := readServerConfigs(backendConfig.ServerNames, kvs)
+	if err != nil {
+		return nil, err
+	}
+
+	var caCertPath, clientCertPath, clientKeyPath string
+	ca, caCertPath, err := ensureCA(kvs)
+	if err != nil {
+		log.Warn("PKI root CA unavailable, backend ", backendConfig.Name, " will connect without mTLS: ", err.Error())
+		caCertPath = ""
+	} else {
+		clientCertPath, clientKeyPath, err = issueBackendClientCert(ca, backendConfig.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error issuing client certificate for backend %s: %s", backendConfig.Name, err.Error())
+		}
+		for i := range servers {
+			servers[i].ClientCertPath = clientCertPath
+			servers[i].ClientKeyPath = clientKeyPath
+		}
+	}
+
+	lb, err := buildLoadBalancer(*backendConfig, caCertPath, servers)
+	if err != nil {
+		return nil, err
+	}
+
+	route := &Route{
+		Name:         name,
+		URIRoot:      routeConfig.URIRoot,
+		Backend:      *backendConfig,
+		LoadBalancer: lb,
+	}
+
+	if backendConfig.Protocol == config.ProtocolGRPC {
+		log.Info("backend ", backendConfig.Name, " is a gRPC backend - building gRPC proxy")
+
+		handler, err := BuildGRPCProxy(*backendConfig, lb, caCertPath, clientCertPath, clientKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		route.Handler = handler
+
+		loadbalancer.RegisterResolverTarget(backendConfig.Name, lb)
+	} else {
+		handler, err := buildHTTPProxy(backendConfig.Name, lb, caCertPath, clientCertPath, clientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("error building HTTP proxy transport for backend %s: %s", backendConfig.Name, err.Error())
+		}
+		route.Handler = handler
+	}
+
+	return route, nil
+}
+
+func readServerConfigs(serverNames []string, kvs kvstore.KVStore) ([]config.ServerConfig, error) {
+	servers := make([]config.ServerConfig, 0, len(serverNames))
+	for _, serverName := range serverNames {
+		server, err := config.ReadServerConfig(serverName, kvs)
+		if err != nil {
+			return nil, fmt.Errorf("error reading server config for %s: %s", serverName, err.Error())
+		}
+		servers = append(servers, *server)
+	}
+	return servers, nil
+}
+
+func buildLoadBalancer(backend config.BackendConfig, caCertPath string, servers []config.ServerConfig) (loadbalancer.LoadBalancer, error) {
+	factory, err := loadbalancer.LoadBalancerFactoryForPolicy(backend.LoadBalancerPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	return factory.NewLoadBalancer(backend.Name, caCertPath, backend.Protocol, servers)
+}
+
+//ensureCA bootstraps (or loads) the cluster's PKI root CA at most once per
+//process and exports its certificate to a file every backend's transport
+//can reference as its CACertPath, regardless of which route asks first.
+func ensureCA(kvs kvstore.KVStore) (*pki.CA, string, error) {
+	caMutex.Lock()
+	defer caMutex.Unlock()
+
+	if cachedCA != nil {
+		return cachedCA, cachedCert, nil
+	}
+
+	ca, err := pki.Bootstrap(kvs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	certPath := filepath.Join(os.TempDir(), "xavi-ca-cert.pem")
+	if err := ca.ExportCert(certPath); err != nil {
+		return nil, "", err
+	}
+
+	cachedCA, cachedCert = ca, certPath
+
+	return ca, certPath, nil
+}
+
+//issueBackendClientCert issues (and keeps rotating in the background) the
+//client certificate xavi presents to backendName's servers for mTLS,
+//returning the paths RotateClientCert writes it to.
+func issueBackendClientCert(ca *pki.CA, backendName string) (certPath, keyPath string, err error) {
+	certDir := os.TempDir()
+
+	_, err = ca.RotateClientCert(backendName, certDir, clientCertRotateInterval, func(certPath, keyPath string) {
+		log.Info("rotated client certificate for backend ", backendName)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return filepath.Join(certDir, backendName+"-cert.pem"), filepath.Join(certDir, backendName+"-key.pem"), nil
+}
+
+//buildHTTPProxy returns the handler used for ordinary HTTP backends: each
+//request is forwarded to whichever address lb currently hands out, over
+//mTLS if the pki subsystem issued this backend a client identity.
+//backendName is used only to label the timer.ServiceCall leaf span
+//recorded for each dispatched request.
+func buildHTTPProxy(backendName string, lb loadbalancer.LoadBalancer, caCertPath, clientCertPath, clientKeyPath string) (http.Handler, error) {
+	transport, err := loadbalancer.BuildTransport(&loadbalancer.LoadBalancerEndpoint{
+		CACertPath:     caCertPath,
+		ClientCertPath: clientCertPath,
+		ClientKeyPath:  clientKeyPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			address, err := lb.GetConnectAddress()
+			if err != nil {
+				log.Error("no available endpoint: ", err.Error())
+				return
+			}
+			r.URL.Scheme = "http"
+			r.URL.Host = address
+
+			if et := timer.TracerFromRequest(r); et != nil {
+				timer.InjectTraceContext(r, et)
+			}
+		},
+		Transport: releasingTransport(tracingTransport(transport, backendName), lb),
+	}, nil
+}
+
+//tracingTransport wraps transport so that, if the request carries a
+//timer.Contributor (see timer.ContextWithContributor), the round trip to
+//whichever address Director picked is recorded as a timer.ServiceCall leaf
+//span under it, with that address as the span's peer.
+func tracingTransport(transport http.RoundTripper, backendName string) http.RoundTripper {
+	return &serviceCallRoundTripper{RoundTripper: transport, backendName: backendName}
+}
+
+type serviceCallRoundTripper struct {
+	http.RoundTripper
+	backendName string
+}
+
+func (t *serviceCallRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	contributor := timer.ContributorFromRequest(r)
+	var sc *timer.ServiceCall
+	if contributor != nil {
+		sc = contributor.StartServiceCall(t.backendName, r.URL.Host)
+	}
+
+	resp, err := t.RoundTripper.RoundTrip(r)
+
+	if sc != nil {
+		sc.End(err)
+	}
+
+	return resp, err
+}
+
+//releasingTransport wraps transport so that, if lb tracks in-flight
+//connections (currently only LeastConnectionsLoadBalancer), the address a
+//request was dispatched to is released back to lb as soon as the round
+//trip to the backend completes. Load balancers that don't implement
+//loadbalancer.ConnectionReleaser are returned unwrapped.
+func releasingTransport(transport http.RoundTripper, lb loadbalancer.LoadBalancer) http.RoundTripper {
+	releaser, ok := lb.(loadbalancer.ConnectionReleaser)
+	if !ok {
+		return transport
+	}
+
+	return &connectionReleasingRoundTripper{RoundTripper: transport, releaser: releaser}
+}
+
+//connectionReleasingRoundTripper decrements releaser's in-flight count for
+//the dispatched address once the round trip to it returns, regardless of
+//whether it succeeded, so a load balancer that counts in-flight requests
+//sees the count come back down instead of only ever growing.
+type connectionReleasingRoundTripper struct {
+	http.RoundTripper
+	releaser loadbalancer.ConnectionReleaser
+}
+
+func (t *connectionReleasingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := t.RoundTripper.RoundTrip(r)
+	t.releaser.ReleaseConnection(r.URL.Host)
+	return resp, err
+}
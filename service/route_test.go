@@ -0,0 +1,108 @@
+package service
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/xtracdev/xavi/config"
+	"github.com/xtracdev/xavi/loadbalancer"
+)
+
+//serverConfigFor parses an httptest.Server's URL into the address/port pair
+//config.ServerConfig and a least-connections LoadBalancer both key off.
+func serverConfigFor(t *testing.T, rawURL string) config.ServerConfig {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host, portStr, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return config.ServerConfig{Address: host, Port: port}
+}
+
+//TestBuildHTTPProxyReleasesLeastConnectionsInFlightCount exercises the real
+//dispatch path (buildHTTPProxy's *httputil.ReverseProxy, not the load
+//balancer in isolation) to prove that a dispatched request's in-flight
+//count is released once it completes. Least connections breaks ties in
+//favor of the first-added server, so if the count returned by each prior
+//request is correctly released back to zero, every sequential request
+//picks the same (first) server; if release were never wired up, the
+//in-flight counts would instead grow in lockstep and requests would
+//alternate between servers.
+func TestBuildHTTPProxyReleasesLeastConnectionsInFlightCount(t *testing.T) {
+	serverA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend", "a")
+	}))
+	defer serverA.Close()
+
+	serverB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend", "b")
+	}))
+	defer serverB.Close()
+
+	factory := &loadbalancer.LeastConnectionsLoadBalancerFactory{}
+	lb, err := factory.NewLoadBalancer("test-backend", "", config.ProtocolHTTP, []config.ServerConfig{
+		serverConfigFor(t, serverA.URL),
+		serverConfigFor(t, serverB.URL),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, err := buildHTTPProxy("test-backend", lb, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxy := httptest.NewServer(handler)
+	defer proxy.Close()
+
+	client := proxy.Client()
+
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get(proxy.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if got := resp.Header.Get("X-Backend"); got != "a" {
+			t.Fatalf("request %d: expected the in-flight count to be released after each request so every tie is broken toward the first server (a), but hit %s", i, got)
+		}
+	}
+}
+
+//TestReleasingTransportSkipsLoadBalancersWithoutInFlightTracking confirms
+//releasingTransport leaves the transport untouched for load balancer
+//policies (round robin, weighted round robin) that don't implement
+//loadbalancer.ConnectionReleaser, since they have no in-flight count to
+//release.
+func TestReleasingTransportSkipsLoadBalancersWithoutInFlightTracking(t *testing.T) {
+	factory := &loadbalancer.RoundRobinLoadBalancerFactory{}
+	lb, err := factory.NewLoadBalancer("test-backend", "", config.ProtocolHTTP, []config.ServerConfig{
+		{Address: "127.0.0.1", Port: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport := &http.Transport{}
+	if got := releasingTransport(transport, lb); got != http.RoundTripper(transport) {
+		t.Fatal("expected releasingTransport to return the transport unwrapped for a load balancer without in-flight tracking")
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/xtracdev/xavi/timer"
+)
+
+//HealthCheckContext carries the identifying information xavi's health
+//check endpoint reports back for a running service.
+type HealthCheckContext struct {
+	ListenerName string
+}
+
+//Service is a runnable xavi listener: an address to listen on and the
+//set of routes it dispatches incoming requests to.
+type Service interface {
+	http.Handler
+	AddRoute(route *Route)
+}
+
+//managedService is the default Service implementation built by
+//BuildServiceForListener: request dispatch is a longest-prefix match
+//over URIRoot against the routes it was configured with.
+type managedService struct {
+	ListenerName       string
+	Address            string
+	HealthCheckContext HealthCheckContext
+	Routes             []*Route
+}
+
+func newManagedService() *managedService {
+	return &managedService{}
+}
+
+//AddRoute appends route to the set this service dispatches to.
+func (m *managedService) AddRoute(route *Route) {
+	m.Routes = append(m.Routes, route)
+}
+
+//ServeHTTP dispatches to the route whose URIRoot is the longest prefix
+//match for the request path. The request is timed end to end as a
+//timer.EndToEndTimer, continuing the caller's trace if it propagated one
+//via W3C traceparent or B3 headers, and the timer is attached to the
+//request's context so the dispatched route can inject it onto the
+//outbound call to the backend. Dispatching to the matched route is itself
+//timed as a timer.Contributor child span, so the backend call the route's
+//handler makes has a contributor to attach its timer.ServiceCall leaf span
+//to.
+func (m *managedService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	et := timer.NewEndToEndTimerWithTraceContext(m.ListenerName, timer.ExtractTraceContext(r))
+	r = r.WithContext(timer.ContextWithTimer(r.Context(), et))
+	defer et.Stop(nil)
+
+	var best *Route
+	for _, route := range m.Routes {
+		if !strings.HasPrefix(r.URL.Path, route.URIRoot) {
+			continue
+		}
+		if best == nil || len(route.URIRoot) > len(best.URIRoot) {
+			best = route
+		}
+	}
+
+	if best == nil || best.Handler == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	contributor := et.StartContributor(best.Name)
+	r = r.WithContext(timer.ContextWithContributor(r.Context(), contributor))
+	defer contributor.End(nil)
+
+	best.Handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xtracdev/xavi/config"
+	"github.com/xtracdev/xavi/loadbalancer"
+	"github.com/xtracdev/xavi/timer"
+)
+
+//TestBuildHTTPProxyRecordsServiceCallUnderContributor proves the backend
+//round trip buildHTTPProxy dispatches is recorded as a timer.ServiceCall
+//leaf span under the request's timer.Contributor, rather than only the
+//flat root EndToEndTimer span every route used to produce.
+func TestBuildHTTPProxyRecordsServiceCallUnderContributor(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	factory := &loadbalancer.RoundRobinLoadBalancerFactory{}
+	lb, err := factory.NewLoadBalancer("trace-test-backend", "", config.ProtocolHTTP, []config.ServerConfig{
+		serverConfigFor(t, backend.URL),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, err := buildHTTPProxy("trace-test-backend", lb, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	et := timer.NewEndToEndTimer("test-listener")
+	contributor := et.StartContributor("test-route")
+
+	req := httptest.NewRequest("GET", "http://trace-test.invalid/", nil)
+	req = req.WithContext(timer.ContextWithContributor(req.Context(), contributor))
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	contributor.End(nil)
+
+	if len(contributor.ServiceCalls) != 1 {
+		t.Fatalf("expected exactly one service call recorded under the contributor, got %d", len(contributor.ServiceCalls))
+	}
+
+	sc := contributor.ServiceCalls[0]
+	if sc.Name != "trace-test-backend" {
+		t.Fatalf("expected service call name %q, got %q", "trace-test-backend", sc.Name)
+	}
+	if sc.Peer == "" {
+		t.Fatal("expected the service call's peer address to be set to the dispatched backend")
+	}
+	if sc.EndTime.IsZero() {
+		t.Fatal("expected the service call to be ended once the round trip completed")
+	}
+}
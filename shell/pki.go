@@ -0,0 +1,55 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/xtracdev/xavi/kvstore"
+	"github.com/xtracdev/xavi/pki"
+)
+
+//PKIBootstrap implements "xavi pki bootstrap": generate the cluster root
+//CA if one does not already exist in the KV store, otherwise report the
+//one that's already there.
+func PKIBootstrap(kvs kvstore.KVStore, out io.Writer) int {
+	ca, err := pki.Bootstrap(kvs)
+	if err != nil {
+		fmt.Fprintln(out, "Error bootstrapping PKI root CA:", err.Error())
+		return 1
+	}
+
+	fmt.Fprintln(out, "PKI root CA ready, subject:", ca.Cert.Subject.CommonName)
+	return 0
+}
+
+//PKIExport implements "xavi pki export <path>": write the cluster root
+//CA's certificate (not its private key) to path so operators can
+//distribute it to clients that need to trust xavi-issued certs.
+func PKIExport(kvs kvstore.KVStore, path string, out io.Writer) int {
+	ca, err := pki.Load(kvs)
+	if err != nil {
+		fmt.Fprintln(out, "Error loading PKI root CA:", err.Error())
+		return 1
+	}
+
+	if err := ca.ExportCert(path); err != nil {
+		fmt.Fprintln(out, "Error exporting PKI root CA:", err.Error())
+		return 1
+	}
+
+	fmt.Fprintln(out, "Exported PKI root CA certificate to", path)
+	return 0
+}
+
+//PKIRotate implements "xavi pki rotate": discard the current root CA and
+//generate a new one. Existing leaf certificates signed by the old CA
+//remain valid until their own expiry or the next scheduled rotation.
+func PKIRotate(kvs kvstore.KVStore, out io.Writer) int {
+	if err := pki.Rotate(kvs); err != nil {
+		fmt.Fprintln(out, "Error rotating PKI root CA:", err.Error())
+		return 1
+	}
+
+	fmt.Fprintln(out, "PKI root CA rotated")
+	return 0
+}
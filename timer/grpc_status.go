@@ -0,0 +1,24 @@
+package timer
+
+import "google.golang.org/grpc/codes"
+
+//EndGRPC stops the service call timer and records the gRPC status code
+//the call finished with, so timer trees for gRPC routes carry gRPC
+//status semantics instead of being forced into an HTTP error string.
+//Any non-OK code is also recorded as the call's Error.
+func (sc *ServiceCall) EndGRPC(code codes.Code) {
+	sc.GRPCStatusCode = code.String()
+
+	var err error
+	if code != codes.OK {
+		err = grpcStatusError(code)
+	}
+
+	sc.End(err)
+}
+
+type grpcStatusError codes.Code
+
+func (e grpcStatusError) Error() string {
+	return codes.Code(e).String()
+}
@@ -0,0 +1,113 @@
+package timer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//OTLPSpanExporter exports spans to an OpenTelemetry collector's OTLP/HTTP
+//JSON endpoint (e.g. http://localhost:4318/v1/traces).
+type OTLPSpanExporter struct {
+	Endpoint    string
+	ServiceName string
+	client      *http.Client
+
+	mutex sync.Mutex
+	spans []Span
+}
+
+//NewOTLPSpanExporter creates an exporter that posts batches of spans to
+//endpoint, tagged with the given service name.
+func NewOTLPSpanExporter(endpoint, serviceName string) *OTLPSpanExporter {
+	return &OTLPSpanExporter{
+		Endpoint:    endpoint,
+		ServiceName: serviceName,
+		client:      &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+//ExportSpan buffers span for the next Flush.
+func (o *OTLPSpanExporter) ExportSpan(span Span) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.spans = append(o.spans, span)
+	return nil
+}
+
+//Flush posts the buffered spans to the OTLP/HTTP endpoint as an
+//OTLP ExportTraceServiceRequest-shaped JSON payload.
+func (o *OTLPSpanExporter) Flush() error {
+	o.mutex.Lock()
+	pending := o.spans
+	o.spans = nil
+	o.mutex.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(otlpExportRequest(o.ServiceName, pending))
+	if err != nil {
+		return err
+	}
+
+	resp, err := o.client.Post(o.Endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP exporter received status %d from %s", resp.StatusCode, o.Endpoint)
+	}
+
+	return nil
+}
+
+func otlpExportRequest(serviceName string, spans []Span) map[string]interface{} {
+	otlpSpans := make([]map[string]interface{}, 0, len(spans))
+	for _, s := range spans {
+		status := map[string]interface{}{"code": "STATUS_CODE_OK"}
+		if s.Error != "" {
+			status = map[string]interface{}{"code": "STATUS_CODE_ERROR", "message": s.Error}
+		}
+
+		attributes := []map[string]interface{}{}
+		if s.Peer != "" {
+			attributes = append(attributes, map[string]interface{}{
+				"key":   "peer.address",
+				"value": map[string]interface{}{"stringValue": s.Peer},
+			})
+		}
+
+		otlpSpans = append(otlpSpans, map[string]interface{}{
+			"traceId":           s.TraceID,
+			"spanId":            s.SpanID,
+			"parentSpanId":      s.ParentSpanID,
+			"name":              s.Name,
+			"startTimeUnixNano": s.StartTime,
+			"endTimeUnixNano":   s.EndTime,
+			"attributes":        attributes,
+			"status":            status,
+		})
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{
+			{
+				"resource": map[string]interface{}{
+					"attributes": []map[string]interface{}{
+						{"key": "service.name", "value": map[string]interface{}{"stringValue": serviceName}},
+					},
+				},
+				"scopeSpans": []map[string]interface{}{
+					{"spans": otlpSpans},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,106 @@
+package timer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+//TraceContext carries the identifiers needed to stitch a new span tree
+//into a trace that started upstream.
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+}
+
+//traceparentHeader is the W3C Trace Context header name, see
+//https://www.w3.org/TR/trace-context/; the X-B3-* names are the Zipkin B3
+//propagation headers.
+const (
+	traceparentHeader = "traceparent"
+	b3TraceIDHeader    = "X-B3-TraceId"
+	b3SpanIDHeader     = "X-B3-SpanId"
+	b3SampledHeader    = "X-B3-Sampled"
+)
+
+//ExtractTraceContext reads a propagated trace context from an incoming
+//request, preferring the W3C traceparent header and falling back to B3.
+func ExtractTraceContext(r *http.Request) *TraceContext {
+	if tp := r.Header.Get(traceparentHeader); tp != "" {
+		if tc := parseTraceparent(tp); tc != nil {
+			return tc
+		}
+	}
+
+	traceID := r.Header.Get(b3TraceIDHeader)
+	spanID := r.Header.Get(b3SpanIDHeader)
+	if traceID != "" && spanID != "" {
+		return &TraceContext{TraceID: traceID, SpanID: spanID}
+	}
+
+	return nil
+}
+
+//InjectTraceContext writes et's current trace identity onto an outbound
+//request as both a W3C traceparent header and B3 headers, so the next hop
+//can continue the same trace.
+func InjectTraceContext(r *http.Request, et *EndToEndTimer) {
+	r.Header.Set(traceparentHeader, fmt.Sprintf("00-%s-%s-01", et.traceID, et.spanID))
+	r.Header.Set(b3TraceIDHeader, et.traceID)
+	r.Header.Set(b3SpanIDHeader, et.spanID)
+	r.Header.Set(b3SampledHeader, "1")
+}
+
+//parseTraceparent parses a W3C traceparent header value of the form
+//version-traceid-spanid-flags.
+func parseTraceparent(header string) *TraceContext {
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 {
+		return nil
+	}
+
+	traceID, spanID := parts[1], parts[2]
+	if len(traceID) != 32 || len(spanID) != 16 {
+		return nil
+	}
+
+	return &TraceContext{TraceID: traceID, SpanID: spanID}
+}
+
+type contextKey int
+
+const (
+	timerContextKey contextKey = iota
+	contributorContextKey
+)
+
+//ContextWithTimer returns a copy of ctx carrying et, so handlers further
+//down the request path can look it up with TracerFromRequest.
+func ContextWithTimer(ctx context.Context, et *EndToEndTimer) context.Context {
+	return context.WithValue(ctx, timerContextKey, et)
+}
+
+//TracerFromRequest returns the EndToEndTimer associated with r, if any,
+//so plugin authors can start their own child contributor/service-call
+//spans under the request's existing trace. Returns nil if no timer has
+//been attached to the request's context.
+func TracerFromRequest(r *http.Request) *EndToEndTimer {
+	et, _ := r.Context().Value(timerContextKey).(*EndToEndTimer)
+	return et
+}
+
+//ContextWithContributor returns a copy of ctx carrying c, so the proxy
+//transport dispatching the request can start a ServiceCall leaf span
+//under it with ContributorFromRequest.
+func ContextWithContributor(ctx context.Context, c *Contributor) context.Context {
+	return context.WithValue(ctx, contributorContextKey, c)
+}
+
+//ContributorFromRequest returns the Contributor associated with r, if any.
+//Returns nil if no contributor has been attached to the request's
+//context, e.g. because the route handling it isn't wrapped in one.
+func ContributorFromRequest(r *http.Request) *Contributor {
+	c, _ := r.Context().Value(contributorContextKey).(*Contributor)
+	return c
+}
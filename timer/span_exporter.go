@@ -0,0 +1,157 @@
+package timer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//flushInterval is how often a background goroutine flushes the installed
+//exporter's buffered spans, so shipping them to the tracing backend never
+//blocks the request that produced the last span in a batch.
+const flushInterval = 2 * time.Second
+
+//Span is the exporter-facing view of one node in a timer tree: an
+//EndToEndTimer, a Contributor, or a ServiceCall.
+type Span struct {
+	Name         string
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Peer         string
+	StartTime    int64 //unix nanos
+	EndTime      int64 //unix nanos
+	Error        string
+}
+
+//SpanExporter receives finished spans as each timer node ends, so a trace
+//can be assembled and shipped to a tracing backend.
+type SpanExporter interface {
+	ExportSpan(span Span) error
+	Flush() error
+}
+
+var (
+	exporterMutex sync.RWMutex
+	exporter      SpanExporter
+	stopFlush     chan struct{}
+)
+
+//SetSpanExporter installs exporter as the destination for every span
+//produced from this point forward, and starts a background goroutine
+//that flushes it every flushInterval. Passing nil disables export and
+//stops the previous exporter's flush goroutine, if any.
+func SetSpanExporter(e SpanExporter) {
+	exporterMutex.Lock()
+	defer exporterMutex.Unlock()
+
+	if stopFlush != nil {
+		close(stopFlush)
+		stopFlush = nil
+	}
+
+	exporter = e
+
+	if e != nil {
+		stopFlush = make(chan struct{})
+		go runBackgroundFlush(e, stopFlush)
+	}
+}
+
+func currentExporter() SpanExporter {
+	exporterMutex.RLock()
+	defer exporterMutex.RUnlock()
+	return exporter
+}
+
+//runBackgroundFlush periodically flushes e's buffered spans until stop is
+//closed, so the HTTP POST a Flush performs never happens inline on the
+//goroutine handling a request.
+func runBackgroundFlush(e SpanExporter, stop chan struct{}) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := e.Flush(); err != nil {
+				log.Warn("error flushing span exporter: ", err.Error())
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func exportSpan(span Span) {
+	e := currentExporter()
+	if e == nil {
+		return
+	}
+
+	if err := e.ExportSpan(span); err != nil {
+		log.Warn("error exporting span ", span.Name, ": ", err.Error())
+	}
+}
+
+func spanFromEndToEndTimer(et *EndToEndTimer) Span {
+	return Span{
+		Name:         et.Name,
+		TraceID:      et.traceID,
+		SpanID:       et.spanID,
+		ParentSpanID: et.parentSpanID,
+		StartTime:    et.StartTime.UnixNano(),
+		EndTime:      et.EndTime.UnixNano(),
+		Error:        et.Error,
+	}
+}
+
+func spanFromContributor(c *Contributor) Span {
+	return Span{
+		Name:         c.Name,
+		TraceID:      c.traceID,
+		SpanID:       c.spanID,
+		ParentSpanID: c.parentSpanID,
+		StartTime:    c.StartTime.UnixNano(),
+		EndTime:      c.EndTime.UnixNano(),
+		Error:        c.Error,
+	}
+}
+
+func spanFromServiceCall(sc *ServiceCall) Span {
+	return Span{
+		Name:         sc.Name,
+		TraceID:      sc.traceID,
+		SpanID:       sc.spanID,
+		ParentSpanID: sc.parentSpanID,
+		Peer:         sc.Peer,
+		StartTime:    sc.StartTime.UnixNano(),
+		EndTime:      sc.EndTime.UnixNano(),
+		Error:        sc.Error,
+	}
+}
+
+var spanCounter uint64
+
+//newTraceID generates a 128-bit W3C trace-context compatible trace id.
+func newTraceID() string {
+	return randomHex(16)
+}
+
+//newSpanID generates a 64-bit W3C trace-context compatible span id.
+func newSpanID() string {
+	atomic.AddUint64(&spanCounter, 1)
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		log.Error("error generating random span id: ", err.Error())
+	}
+	return hex.EncodeToString(b)
+}
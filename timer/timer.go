@@ -0,0 +1,214 @@
+//Package timer models the timing tree captured for a single request as it
+//flows through xavi: an EndToEndTimer is the root span, a Contributor is a
+//child span for one stage of route processing, and a ServiceCall is a leaf
+//span for a single call out to a backend peer.
+package timer
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+//ServiceCall times a single outbound call to a backend peer.
+type ServiceCall struct {
+	Name      string
+	Peer      string
+	StartTime time.Time
+	EndTime   time.Time
+	Duration  time.Duration
+	Error     string
+
+	//GRPCStatusCode is set by EndGRPC for service calls made to a gRPC
+	//backend, recording the gRPC status the call finished with.
+	GRPCStatusCode string
+
+	spanID       string
+	parentSpanID string
+	traceID      string
+}
+
+//StartServiceCall starts timing a call to peer made on behalf of this
+//contributor, recording it as a leaf span under the contributor.
+func (c *Contributor) StartServiceCall(name, peer string) *ServiceCall {
+	sc := &ServiceCall{
+		Name:         name,
+		Peer:         peer,
+		StartTime:    time.Now(),
+		traceID:      c.traceID,
+		parentSpanID: c.spanID,
+		spanID:       newSpanID(),
+	}
+
+	c.mutex.Lock()
+	c.ServiceCalls = append(c.ServiceCalls, sc)
+	c.mutex.Unlock()
+
+	return sc
+}
+
+//End stops the service call timer, recording err if non-nil, and exports
+//the finished leaf span.
+func (sc *ServiceCall) End(err error) {
+	sc.EndTime = time.Now()
+	sc.Duration = sc.EndTime.Sub(sc.StartTime)
+	if err != nil {
+		sc.Error = err.Error()
+	}
+
+	exportSpan(spanFromServiceCall(sc))
+}
+
+//Contributor times one stage of processing a request, e.g. a single route
+//handler, and may itself make one or more service calls to backends.
+type Contributor struct {
+	Name      string
+	StartTime time.Time
+	EndTime   time.Time
+	Duration  time.Duration
+	Error     string
+
+	ServiceCalls []*ServiceCall
+
+	spanID       string
+	parentSpanID string
+	traceID      string
+	mutex        sync.Mutex
+}
+
+//StartContributor starts timing a new contributor stage under the end to
+//end timer.
+func (et *EndToEndTimer) StartContributor(name string) *Contributor {
+	c := &Contributor{
+		Name:         name,
+		StartTime:    time.Now(),
+		traceID:      et.traceID,
+		parentSpanID: et.spanID,
+		spanID:       newSpanID(),
+	}
+
+	et.mutex.Lock()
+	et.Contributors = append(et.Contributors, c)
+	et.mutex.Unlock()
+
+	return c
+}
+
+//End stops the contributor timer, recording err if non-nil, and exports
+//the finished span.
+func (c *Contributor) End(err error) {
+	c.EndTime = time.Now()
+	c.Duration = c.EndTime.Sub(c.StartTime)
+	if err != nil {
+		c.Error = err.Error()
+	}
+
+	exportSpan(spanFromContributor(c))
+}
+
+//errors returns the non-nil errors recorded against this contributor and
+//its service calls.
+func (c *Contributor) errors() []error {
+	var errs []error
+	if c.Error != "" {
+		errs = append(errs, errString(c.Error))
+	}
+	for _, sc := range c.ServiceCalls {
+		if sc.Error != "" {
+			errs = append(errs, errString(sc.Error))
+		}
+	}
+	return errs
+}
+
+//EndToEndTimer is the root span for a single request as it is processed
+//end to end by xavi.
+type EndToEndTimer struct {
+	Name      string
+	StartTime time.Time
+	EndTime   time.Time
+	Duration  time.Duration
+	Error     string
+	ErrorFree bool
+
+	Contributors []*Contributor
+
+	spanID       string
+	parentSpanID string
+	traceID      string
+	mutex        sync.Mutex
+}
+
+//NewEndToEndTimer starts timing a new end to end request under a fresh
+//trace id.
+func NewEndToEndTimer(name string) *EndToEndTimer {
+	return &EndToEndTimer{
+		Name:      name,
+		StartTime: time.Now(),
+		traceID:   newTraceID(),
+		spanID:    newSpanID(),
+	}
+}
+
+//NewEndToEndTimerWithTraceContext starts timing a new end to end request
+//under the given propagated trace context, stitching this hop into a trace
+//that started upstream.
+func NewEndToEndTimerWithTraceContext(name string, tc *TraceContext) *EndToEndTimer {
+	et := NewEndToEndTimer(name)
+	if tc != nil {
+		et.traceID = tc.TraceID
+		et.parentSpanID = tc.SpanID
+	}
+	return et
+}
+
+//Stop stops the end to end timer, recording err if non-nil, computing
+//ErrorFree across the whole tree, and exporting the finished root span.
+func (et *EndToEndTimer) Stop(err error) {
+	et.EndTime = time.Now()
+	et.Duration = et.EndTime.Sub(et.StartTime)
+	if err != nil {
+		et.Error = err.Error()
+	}
+
+	et.ErrorFree = len(et.ContributorErrors()) == 0 && et.Error == ""
+
+	//exportSpan only buffers the finished span; runBackgroundFlush ships
+	//buffered spans to the tracing backend on its own schedule so Stop
+	//never blocks the request on an outbound HTTP call.
+	exportSpan(spanFromEndToEndTimer(et))
+}
+
+//ContributorErrors returns the errors recorded against every contributor
+//and service call in the tree.
+func (et *EndToEndTimer) ContributorErrors() []error {
+	var errs []error
+	for _, c := range et.Contributors {
+		errs = append(errs, c.errors()...)
+	}
+	return errs
+}
+
+//ToJSONString renders the timer tree as a JSON string, e.g. for logging.
+func (et *EndToEndTimer) ToJSONString() string {
+	b, err := json.Marshal(et)
+	if err != nil {
+		log.Error("error marshalling timer tree: ", err.Error())
+		return "{}"
+	}
+	return string(b)
+}
+
+func errString(s string) error {
+	return &timerError{s}
+}
+
+type timerError struct {
+	msg string
+}
+
+func (e *timerError) Error() string {
+	return e.msg
+}
@@ -0,0 +1,105 @@
+package timer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//ZipkinSpanExporter exports spans to a Zipkin (or Jaeger, which speaks the
+//same v2 JSON API) HTTP collector endpoint, e.g.
+//http://localhost:9411/api/v2/spans.
+type ZipkinSpanExporter struct {
+	Endpoint    string
+	ServiceName string
+	client      *http.Client
+
+	mutex sync.Mutex
+	spans []Span
+}
+
+//NewZipkinSpanExporter creates an exporter that posts batches of spans to
+//endpoint, tagged with the given service name.
+func NewZipkinSpanExporter(endpoint, serviceName string) *ZipkinSpanExporter {
+	return &ZipkinSpanExporter{
+		Endpoint:    endpoint,
+		ServiceName: serviceName,
+		client:      &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+//ExportSpan buffers span for the next Flush.
+func (z *ZipkinSpanExporter) ExportSpan(span Span) error {
+	z.mutex.Lock()
+	defer z.mutex.Unlock()
+	z.spans = append(z.spans, span)
+	return nil
+}
+
+type zipkinSpan struct {
+	TraceID       string            `json:"traceId"`
+	ID            string            `json:"id"`
+	ParentID      string            `json:"parentId,omitempty"`
+	Name          string            `json:"name"`
+	Timestamp     int64             `json:"timestamp"`
+	Duration      int64             `json:"duration"`
+	LocalEndpoint zipkinEndpoint    `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint  `json:"remoteEndpoint,omitempty"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+//Flush posts the buffered spans to the Zipkin v2 HTTP endpoint.
+func (z *ZipkinSpanExporter) Flush() error {
+	z.mutex.Lock()
+	pending := z.spans
+	z.spans = nil
+	z.mutex.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	zspans := make([]zipkinSpan, 0, len(pending))
+	for _, s := range pending {
+		zs := zipkinSpan{
+			TraceID:       s.TraceID,
+			ID:            s.SpanID,
+			ParentID:      s.ParentSpanID,
+			Name:          s.Name,
+			Timestamp:     s.StartTime / int64(time.Microsecond),
+			Duration:      (s.EndTime - s.StartTime) / int64(time.Microsecond),
+			LocalEndpoint: zipkinEndpoint{ServiceName: z.ServiceName},
+		}
+		if s.Peer != "" {
+			zs.RemoteEndpoint = &zipkinEndpoint{ServiceName: s.Peer}
+		}
+		if s.Error != "" {
+			zs.Tags = map[string]string{"error": s.Error}
+		}
+		zspans = append(zspans, zs)
+	}
+
+	body, err := json.Marshal(zspans)
+	if err != nil {
+		return err
+	}
+
+	resp, err := z.client.Post(z.Endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Zipkin exporter received status %d from %s", resp.StatusCode, z.Endpoint)
+	}
+
+	return nil
+}